@@ -0,0 +1,162 @@
+// Package chr converts between images and the 2bpp planar tile format
+// read from the PPU's pattern tables.
+package chr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+// EncodeTile converts an 8x8 tile of pixel values 0-3 into 16 bytes of
+// CHR data: eight bytes of bit 0 followed by eight bytes of bit 1, one
+// byte per row with the leftmost pixel in bit 7.
+func EncodeTile(pixels *[8][8]uint8) (tile [16]uint8) {
+	for row := 0; row < 8; row++ {
+		for col := 0; col < 8; col++ {
+			value := pixels[row][col]
+
+			tile[row] |= (value & 0x01) << uint(7-col)
+			tile[row+8] |= ((value >> 1) & 0x01) << uint(7-col)
+		}
+	}
+
+	return
+}
+
+func DecodeTile(tile []uint8) (pixels [8][8]uint8) {
+	for row := 0; row < 8; row++ {
+		for col := 0; col < 8; col++ {
+			pixels[row][col] = (tile[row]>>uint(7-col))&0x01 | ((tile[row+8]>>uint(7-col))&0x01)<<1
+		}
+	}
+
+	return
+}
+
+// Options control Encode.  If Palette is nil, paletted images are
+// encoded using their color indices, which must all be below 4, and
+// other images are matched against rp2cgo2.PatternTableColors.  When
+// Tall is set tiles are read in the order DumpPatternTables draws 8x16
+// sprites, each even tile above the odd tile following it.
+type Options struct {
+	Palette []color.Color
+	Tall    bool
+}
+
+// Encode converts img, whose width and height must be multiples of 8
+// (16 for the height when Tall is set), to CHR data with tiles ordered
+// left to right, top to bottom.  Every pixel must exactly match one of
+// the palette's colors; fully transparent pixels are encoded as 0.
+func Encode(img image.Image, options *Options) (data []uint8, err error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	bounds := img.Bounds()
+	tileHeight := 8
+
+	if options.Tall {
+		tileHeight = 16
+	}
+
+	if bounds.Dx()%8 != 0 || bounds.Dy()%tileHeight != 0 {
+		err = fmt.Errorf("chr: image is %dx%d, not a multiple of 8x%d", bounds.Dx(), bounds.Dy(), tileHeight)
+		return
+	}
+
+	paletted, indexed := img.(*image.Paletted)
+	palette := options.Palette
+
+	if palette != nil {
+		indexed = false
+	} else {
+		for _, c := range rp2cgo2.PatternTableColors {
+			palette = append(palette, c)
+		}
+	}
+
+	value := func(x, y int) (uint8, error) {
+		if indexed {
+			index := paletted.ColorIndexAt(x, y)
+
+			if index > 3 {
+				return 0, fmt.Errorf("chr: pixel %d,%d has color index %d", x, y, index)
+			}
+
+			return index, nil
+		}
+
+		c := img.At(x, y)
+
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return 0, nil
+		}
+
+		r, g, b, a := c.RGBA()
+
+		for i, p := range palette {
+			pr, pg, pb, pa := p.RGBA()
+
+			if r == pr && g == pg && b == pb && a == pa {
+				return uint8(i), nil
+			}
+		}
+
+		return 0, fmt.Errorf("chr: pixel %d,%d color %v is not in the palette", x, y, c)
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += tileHeight {
+		for x := bounds.Min.X; x < bounds.Max.X; x += 8 {
+			for top := 0; top < tileHeight; top += 8 {
+				pixels := [8][8]uint8{}
+
+				for row := 0; row < 8; row++ {
+					for col := 0; col < 8; col++ {
+						if pixels[row][col], err = value(x+col, y+top+row); err != nil {
+							return nil, err
+						}
+					}
+				}
+
+				tile := EncodeTile(&pixels)
+				data = append(data, tile[:]...)
+			}
+		}
+	}
+
+	return
+}
+
+// ParsePalette parses four comma separated hexadecimal NES color
+// indices, such as "0f,16,27,30", into colors from
+// rp2cgo2.DefaultPalette.  An empty string gives
+// rp2cgo2.PatternTableColors.
+func ParsePalette(s string) (colors [4]color.RGBA, err error) {
+	if s == "" {
+		return rp2cgo2.PatternTableColors, nil
+	}
+
+	fields := strings.Split(s, ",")
+
+	if len(fields) != 4 {
+		err = fmt.Errorf("chr: palette must have 4 colors not %d", len(fields))
+		return
+	}
+
+	for i, field := range fields {
+		index, err := strconv.ParseUint(strings.TrimSpace(field), 16, 8)
+
+		if err != nil || index > 0x3f {
+			return colors, fmt.Errorf("chr: invalid palette index %q", field)
+		}
+
+		colors[i] = rp2cgo2.DefaultPalette.RGBA(uint8(index))
+	}
+
+	return
+}
@@ -0,0 +1,116 @@
+package chr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+func TestEncodeTile(t *testing.T) {
+	pixels := [8][8]uint8{}
+	pixels[0][0] = 1
+	pixels[0][7] = 2
+	pixels[7][3] = 3
+
+	tile := EncodeTile(&pixels)
+
+	if tile[0] != 0x80 || tile[8] != 0x01 || tile[7] != 0x10 || tile[15] != 0x10 {
+		t.Errorf("Tile is % 02X", tile)
+	}
+
+	if DecodeTile(tile[:]) != pixels {
+		t.Error("Decoded tile does not match")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	ppu := rp2cgo2.NewRP2C02(nil)
+
+	for address := uint16(0x0000); address < 0x2000; address++ {
+		ppu.Memory.Store(address, uint8(address*13))
+	}
+
+	for _, tall := range []bool{false, true} {
+		left, right := ppu.DumpPatternTables(rp2cgo2.PatternTableColors, tall)
+
+		for i, img := range []*image.RGBA{left, right} {
+			data, err := Encode(img, &Options{Tall: tall})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(data) != 0x1000 {
+				t.Fatalf("Data is %d bytes not 4096", len(data))
+			}
+
+			for offset, value := range data {
+				address := uint16(i*0x1000 + offset)
+
+				if value != ppu.Memory.Fetch(address) {
+					t.Fatalf("Tall %v: %04X is %02X not %02X", tall, address, value, ppu.Memory.Fetch(address))
+				}
+			}
+		}
+	}
+}
+
+func TestEncodePaletted(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{
+		color.Black, color.White, color.Gray{0x80}, color.Gray{0x40}, color.Gray{0x20},
+	})
+	img.SetColorIndex(1, 0, 3)
+
+	data, err := Encode(img, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data[0] != 0x40 || data[8] != 0x40 {
+		t.Errorf("Data is % 02X", data)
+	}
+
+	img.SetColorIndex(2, 0, 4)
+
+	if _, err = Encode(img, nil); err == nil {
+		t.Error("Color index 4 was accepted")
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	if _, err := Encode(image.NewRGBA(image.Rect(0, 0, 8, 12)), nil); err == nil {
+		t.Error("8x12 image was accepted")
+	}
+
+	if _, err := Encode(image.NewRGBA(image.Rect(0, 0, 8, 8)), &Options{Tall: true}); err == nil {
+		t.Error("8x8 image was accepted for 8x16 tiles")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	img.Set(0, 0, color.RGBA{1, 2, 3, 255})
+
+	if _, err := Encode(img, nil); err == nil {
+		t.Error("Color outside the palette was accepted")
+	}
+}
+
+func TestParsePalette(t *testing.T) {
+	colors, err := ParsePalette("0f, 16,27,30")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if colors[1] != rp2cgo2.DefaultPalette[0x16] {
+		t.Errorf("Color is %v not %v", colors[1], rp2cgo2.DefaultPalette[0x16])
+	}
+
+	for _, s := range []string{"0f,16,27", "0f,16,27,40", "0f,16,27,zz"} {
+		if _, err := ParsePalette(s); err == nil {
+			t.Errorf("Palette %q was accepted", s)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Command chrdump writes the CHR banks of an iNES or NES 2.0 ROM as PNG
+// sheets.
+//
+// Usage:
+//
+//	chrdump [-o dir] [-palette 0f,16,27,30] [-4k] [-8x16] rom.nes
+//
+// Each 8KB bank is written as a 256x128 sheet holding both pattern
+// tables, or with -4k each 4KB half is written as its own 128x128
+// sheet.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nwidger/rp2cgo2"
+	"github.com/nwidger/rp2cgo2/chr"
+	"github.com/nwidger/rp2cgo2/ines"
+)
+
+func writePNG(filename string, img image.Image) error {
+	f, err := os.Create(filename)
+
+	if err != nil {
+		return err
+	}
+
+	if err = png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func main() {
+	dir := flag.String("o", ".", "output directory")
+	palette := flag.String("palette", "", "four comma separated hex NES color indices, e.g. 0f,16,27,30")
+	halves := flag.Bool("4k", false, "write each 4KB half as a separate sheet")
+	tall := flag.Bool("8x16", false, "pair tiles as 8x16 sprites")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] rom.nes\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	colors, err := chr.ParsePalette(*palette)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rom, err := ines.Load(flag.Arg(0))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if rom.CHR == nil || rom.CHR.RAM {
+		log.Fatalf("%s has no CHR ROM", flag.Arg(0))
+	}
+
+	ppu := rp2cgo2.NewRP2C02WithConfig(rom.Config(rp2cgo2.Config{}))
+	base := strings.TrimSuffix(filepath.Base(flag.Arg(0)), filepath.Ext(flag.Arg(0)))
+
+	for bank := 0; bank < (rom.CHR.NumBanks()+1)/2; bank++ {
+		rom.CHR.Select8K(bank)
+		left, right := ppu.DumpPatternTables(colors, *tall)
+
+		if *halves {
+			for half, img := range []*image.RGBA{left, right} {
+				if bank*2+half >= rom.CHR.NumBanks() {
+					break
+				}
+
+				filename := filepath.Join(*dir, fmt.Sprintf("%s-4k-%03d.png", base, bank*2+half))
+
+				if err := writePNG(filename, img); err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			continue
+		}
+
+		sheet := image.NewRGBA(image.Rect(0, 0, 256, 128))
+		draw.Draw(sheet, left.Bounds(), left, image.Point{}, draw.Src)
+		draw.Draw(sheet, right.Bounds().Add(image.Pt(128, 0)), right, image.Point{}, draw.Src)
+
+		filename := filepath.Join(*dir, fmt.Sprintf("%s-8k-%03d.png", base, bank))
+
+		if err := writePNG(filename, sheet); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
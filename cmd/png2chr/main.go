@@ -0,0 +1,83 @@
+// Command png2chr converts a PNG image into CHR data.
+//
+// Usage:
+//
+//	png2chr [-o out.chr] [-palette 0f,16,27,30] [-8x16] art.png
+//
+// Paletted images are converted using their color indices unless
+// -palette is given.  Other images must only use the four palette
+// colors, by default those chrdump draws with.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nwidger/rp2cgo2/chr"
+)
+
+func main() {
+	output := flag.String("o", "", "output file, defaults to the input with a .chr extension")
+	palette := flag.String("palette", "", "four comma separated hex NES color indices, e.g. 0f,16,27,30")
+	tall := flag.Bool("8x16", false, "read tiles as 8x16 sprite pairs")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] art.png\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	options := &chr.Options{Tall: *tall}
+
+	if *palette != "" {
+		colors, err := chr.ParsePalette(*palette)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, c := range colors {
+			options.Palette = append(options.Palette, color.Color(c))
+		}
+	}
+
+	f, err := os.Open(flag.Arg(0))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	img, err := png.Decode(f)
+	f.Close()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := chr.Encode(img, options)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(flag.Arg(0), filepath.Ext(flag.Arg(0))) + ".chr"
+	}
+
+	if err = ioutil.WriteFile(*output, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,326 @@
+// Command termview renders PPU frames in a terminal using half-block
+// characters and 24-bit ANSI colors, two pixels to each character.
+//
+// Usage:
+//
+//	termview [flags] chr
+//
+// chr is either an iNES or NES 2.0 ROM or a raw CHR dump.  A stub CPU
+// uploads the palette and nametables through $2006/$2007 and on every
+// NMI writes OAM, the scroll position, $2000 and $2001, as a game's NMI
+// handler would.  With -frames greater than one the scroll position
+// advances by -dx and -dy each frame and the frames are drawn in place.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nwidger/rp2cgo2"
+	"github.com/nwidger/rp2cgo2/ines"
+	"github.com/nwidger/rp2cgo2/nametable"
+)
+
+// cpu stands in for the CPU, writing to the PPU's registers the way a
+// simple game would.
+type cpu struct {
+	ppu        *rp2cgo2.RP2C02
+	controller uint8
+	mask       uint8
+	x, y       int
+	dx, dy     int
+	oam        []uint8
+}
+
+func (c *cpu) upload(address uint16, data []uint8) {
+	c.ppu.Store(0x2006, uint8(address>>8))
+	c.ppu.Store(0x2006, uint8(address))
+
+	for _, value := range data {
+		c.ppu.Store(0x2007, value)
+	}
+}
+
+// nmi is the NMI handler, run at the start of every vertical blank.
+func (c *cpu) nmi(state bool) {
+	if !state {
+		return
+	}
+
+	if c.oam != nil {
+		c.ppu.Store(0x2003, 0x00)
+
+		for _, value := range c.oam {
+			c.ppu.Store(0x2004, value)
+		}
+	}
+
+	// the scroll position is 512x480 across the four nametables, with
+	// the nametable select bits of $2000 as its high bits
+	x, y := c.x&0x1ff, c.y%480
+
+	if y < 0 {
+		y += 480
+	}
+
+	nt := uint8(x>>8) | uint8(y/240)<<1
+
+	c.ppu.Store(0x2000, c.controller&0xfc|nt|0x80)
+	c.ppu.Store(0x2005, uint8(x))
+	c.ppu.Store(0x2005, uint8(y%240))
+	c.ppu.Store(0x2001, c.mask)
+
+	c.x += c.dx
+	c.y += c.dy
+}
+
+func readFile(filename string, size int) (data []uint8, err error) {
+	if data, err = os.ReadFile(filename); err != nil {
+		return
+	}
+
+	if len(data) != size {
+		err = fmt.Errorf("%s is %d bytes not %d", filename, len(data), size)
+	}
+
+	return
+}
+
+func loadCHR(filename string, config *rp2cgo2.Config) error {
+	f, err := os.Open(filename)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	rom, err := ines.Read(f)
+
+	switch {
+	case err == nil:
+		*config = rom.Config(*config)
+		return nil
+	case !errors.Is(err, ines.ErrMagic):
+		return err
+	}
+
+	data, err := os.ReadFile(filename)
+
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 || len(data)%0x1000 != 0 {
+		return fmt.Errorf("%s is %d bytes, not a multiple of 4KB", filename, len(data))
+	}
+
+	config.Patterns = ines.NewCHR(data, false)
+
+	return nil
+}
+
+func parseHex(s string) (value uint8, err error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 8)
+	return uint8(v), err
+}
+
+// draw writes img using the upper half block, whose foreground color
+// is the top pixel and background color the bottom pixel.  columns, if
+// non-zero, scales the image to that many characters wide.
+func draw(w *bufio.Writer, img *image.Paletted, columns int) {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+
+	if columns <= 0 || columns > width {
+		columns = width
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 2 {
+		for x := 0; x < columns; x++ {
+			sx := bounds.Min.X + x*width/columns
+			top := img.At(sx, y)
+			bottom := top
+
+			if y+1 < bounds.Max.Y {
+				bottom = img.At(sx, y+1)
+			}
+
+			tr, tg, tb, _ := top.RGBA()
+			br, bg, bb, _ := bottom.RGBA()
+
+			fmt.Fprintf(w, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", tr>>8, tg>>8, tb>>8, br>>8, bg>>8, bb>>8)
+		}
+
+		w.WriteString("\x1b[0m\n")
+	}
+}
+
+// restore resets the colors and shows the cursor, which is hidden while
+// frames are drawn in place.
+func restore() {
+	os.Stdout.WriteString("\x1b[0m\x1b[?25h")
+}
+
+func main() {
+	nams := flag.String("nam", "", "comma separated .nam files (960 or 1024 bytes) for nametables 0-3")
+	palette := flag.String("palette", "", "32 byte palette RAM dump")
+	oam := flag.String("oam", "", "256 byte OAM dump")
+	controller := flag.String("ctrl", "10", "hex value written to $2000, nametable bits are taken from the scroll position")
+	mask := flag.String("mask", "1e", "hex value written to $2001")
+	x := flag.Int("x", 0, "initial horizontal scroll")
+	y := flag.Int("y", 0, "initial vertical scroll")
+	dx := flag.Int("dx", 0, "horizontal scroll per frame")
+	dy := flag.Int("dy", 0, "vertical scroll per frame")
+	frames := flag.Int("frames", 1, "number of frames to draw")
+	fps := flag.Float64("fps", 60, "frames per second when drawing more than one frame")
+	overscan := flag.Bool("overscan", false, "crop the top and bottom 8 lines")
+	aspect := flag.Bool("aspect", false, "stretch to the 8:7 pixel aspect ratio")
+	columns := flag.Int("columns", 0, "scale to this many characters wide")
+	scanline := flag.Bool("scanline", false, "use the scanline renderer")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] chr\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c := &cpu{x: *x, y: *y, dx: *dx, dy: *dy}
+	config := rp2cgo2.Config{Interrupt: c.nmi, Mirroring: rp2cgo2.FourScreen}
+
+	if *scanline {
+		config.Renderer = rp2cgo2.ScanlineRenderer
+	}
+
+	var err error
+
+	if c.controller, err = parseHex(*controller); err != nil {
+		log.Fatalf("invalid -ctrl: %v", err)
+	}
+
+	if c.mask, err = parseHex(*mask); err != nil {
+		log.Fatalf("invalid -mask: %v", err)
+	}
+
+	if !(*fps > 0) {
+		log.Fatalf("invalid -fps: %v is not positive", *fps)
+	}
+
+	if err = loadCHR(flag.Arg(0), &config); err != nil {
+		log.Fatal(err)
+	}
+
+	if *oam != "" {
+		if c.oam, err = readFile(*oam, 256); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	colors := []uint8{
+		0x0f, 0x00, 0x10, 0x30, 0x0f, 0x06, 0x16, 0x26, 0x0f, 0x09, 0x19, 0x29, 0x0f, 0x01, 0x11, 0x21,
+		0x0f, 0x00, 0x10, 0x30, 0x0f, 0x06, 0x16, 0x26, 0x0f, 0x09, 0x19, 0x29, 0x0f, 0x01, 0x11, 0x21,
+	}
+
+	if *palette != "" {
+		if colors, err = readFile(*palette, 32); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ppu := rp2cgo2.NewRP2C02WithConfig(config)
+	c.ppu = ppu
+
+	ppu.PowerOn()
+
+	// register writes are ignored until the end of the first frame
+	ppu.RenderFrame()
+
+	c.upload(0x3f00, colors)
+
+	if *nams != "" {
+		for i, filename := range strings.Split(*nams, ",") {
+			if i >= 4 {
+				log.Fatal("at most four nametables can be loaded")
+			}
+
+			f, err := os.Open(filename)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			nt := &nametable.Nametable{}
+			err = nt.ReadNAM(f)
+			f.Close()
+
+			if err != nil {
+				log.Fatalf("%s: %v", filename, err)
+			}
+
+			c.upload(0x2000+uint16(i)*0x400, append(nt.Tiles[:], nt.Attributes[:]...))
+		}
+	}
+
+	// enable NMI, the first NMI then turns rendering on
+	ppu.Store(0x2000, 0x80)
+	ppu.RenderFrame()
+
+	options := &rp2cgo2.ScreenshotOptions{CropOverscan: *overscan, AspectCorrect: *aspect}
+	w := bufio.NewWriter(os.Stdout)
+	interval := time.Duration(float64(time.Second) / *fps)
+
+	// an interrupt stops drawing between frames so that the cursor is
+	// restored before exiting
+	interrupt := make(chan os.Signal, 1)
+
+	if *frames > 1 {
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		w.WriteString("\x1b[2J\x1b[?25l")
+		defer restore()
+	}
+
+	next := time.Now()
+
+	for i := 0; i < *frames; i++ {
+		frame := &rp2cgo2.Frame{Number: uint16(i), Pixels: ppu.RenderFrame()}
+
+		if *frames > 1 {
+			w.WriteString("\x1b[H")
+		}
+
+		draw(w, frame.Image(ppu.Palette, options), *columns)
+
+		if err := w.Flush(); err != nil {
+			if *frames > 1 {
+				restore()
+			}
+
+			log.Fatal(err)
+		}
+
+		if next = next.Add(interval); i < *frames-1 {
+			select {
+			case <-interrupt:
+				restore()
+				os.Exit(130)
+			case <-time.After(time.Until(next)):
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package rp2cgo2
+
+import "github.com/nwidger/m65go2"
+
+// Config describes how to build an RP2C02.  The zero value gives an
+// NTSC PPU with internal CHR RAM, horizontal mirroring, the default
+// palette, the dot renderer and a new FrameBuffer.
+//
+// Patterns is the cartridge's CHR bus mapped at 0x0000 - 0x1fff.
+// Frames receives completed frames; Output, if set, additionally
+// receives each frame through the legacy blocking handshake.  RAM and
+// RAMSeed select the RAM contents set by PowerOn.  SwapRegisters
+// exchanges $2000 and $2001 as on the RC2C05.
+type Config struct {
+	Interrupt func(state bool)
+	Region    Region
+	Mirroring Mirroring
+	Patterns  m65go2.Memory
+	Palette   *Palette
+	Renderer  Renderer
+	Frames    *FrameBuffer
+	Output    chan []uint8
+	RAM       RAMPattern
+	RAMSeed   int64
+
+	SwapRegisters bool
+}
+
+func NewRP2C02WithConfig(config Config) *RP2C02 {
+	if config.Palette == nil {
+		config.Palette = &DefaultPalette
+	}
+
+	if config.Frames == nil {
+		config.Frames = NewFrameBuffer()
+	}
+
+	mem := NewMemory()
+	mem.Mirroring = config.Mirroring
+	mem.Patterns = config.Patterns
+
+	return &RP2C02{
+		Output:     config.Output,
+		Frames:     config.Frames,
+		Memory:     mem,
+		Palette:    config.Palette,
+		Interrupt:  config.Interrupt,
+		oam:        NewOAM(),
+		Cycles:     make(chan uint16),
+		Region:     config.Region,
+		schedule:   schedules[config.Region],
+		Renderer:   config.Renderer,
+		RAMPattern: config.RAM,
+		RAMSeed:    config.RAMSeed,
+
+		SwapRegisters: config.SwapRegisters,
+	}
+}
@@ -0,0 +1,100 @@
+package rp2cgo2
+
+import (
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/nwidger/m65go2"
+)
+
+func TestNewRP2C02WithConfig(t *testing.T) {
+	patterns := m65go2.NewBasicMemory(0x2000)
+	patterns.Store(0x0123, 0xaa)
+
+	ppu := NewRP2C02WithConfig(Config{
+		Region:    PAL,
+		Mirroring: Vertical,
+		Patterns:  patterns,
+		Renderer:  ScanlineRenderer,
+	})
+
+	if ppu.Region != PAL || ppu.schedule != schedules[PAL] {
+		t.Errorf("Region is %v not PAL", ppu.Region)
+	}
+
+	if ppu.Memory.Mirroring != Vertical {
+		t.Errorf("Mirroring is %v not Vertical", ppu.Memory.Mirroring)
+	}
+
+	if ppu.Memory.Fetch(0x0123) != 0xaa {
+		t.Errorf("Memory is %02X not 0xaa", ppu.Memory.Fetch(0x0123))
+	}
+
+	if ppu.Renderer != ScanlineRenderer {
+		t.Errorf("Renderer is %v not Scanline", ppu.Renderer)
+	}
+
+	if ppu.Palette != &DefaultPalette || ppu.Frames == nil || ppu.Output != nil {
+		t.Error("Palette, Frames and Output are not defaulted")
+	}
+
+	if ppu.Palette.RGBA(0x40|0x20) != DefaultPalette[0x20] {
+		t.Error("Palette index is not masked to 0x3f")
+	}
+}
+
+func TestRGBPalette(t *testing.T) {
+	for _, test := range []struct {
+		index uint8
+		color color.RGBA
+	}{
+		{0x0f, color.RGBA{0, 0, 0, 0xff}},
+		{0x20, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{0x16, color.RGBA{0xff, 0, 0, 0xff}},
+		{0x01, color.RGBA{0, 0x24, 0x91, 0xff}},
+	} {
+		if c := RGBPalette.RGBA(test.index); c != test.color {
+			t.Errorf("Color %02X is %v not %v", test.index, c, test.color)
+		}
+	}
+}
+
+func TestNewRP2C02(t *testing.T) {
+	ppu := NewRP2C02(nil)
+
+	if ppu.Region != NTSC || ppu.Memory.Mirroring != FourScreen || ppu.Renderer != DotRenderer {
+		t.Errorf("Config is %v, %v, %v not NTSC, FourScreen, Dot", ppu.Region, ppu.Memory.Mirroring, ppu.Renderer)
+	}
+
+	if ppu.Output == nil || ppu.Frames == nil {
+		t.Error("Output and Frames are not both created")
+	}
+}
+
+func TestNewRP2C02Output(t *testing.T) {
+	ppu := NewRP2C02(nil)
+	ppu.Registers.Mask = uint8(ShowBackground)
+
+	go ppu.Run()
+
+	go func() {
+		for {
+			ppu.Cycles <- 0xffff
+			<-ppu.Cycles
+		}
+	}()
+
+	for frame := 0; frame < 2; frame++ {
+		select {
+		case colors := <-ppu.Output:
+			if len(colors) != 256*240 {
+				t.Errorf("Frame %d has %d pixels not %d", frame, len(colors), 256*240)
+			}
+
+			ppu.Output <- nil
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for frame %d on Output", frame)
+		}
+	}
+}
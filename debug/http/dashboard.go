@@ -0,0 +1,102 @@
+package http
+
+import "net/http"
+
+// page polls every endpoint, reloading the images and re-rendering the
+// JSON twice a second.
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>rp2cgo2</title>
+<style>
+body { background: #222; color: #ddd; font: 12px monospace; margin: 1em; }
+section { display: inline-block; vertical-align: top; margin: 0 1em 1em 0; }
+img { image-rendering: pixelated; border: 1px solid #555; }
+table { border-collapse: collapse; }
+td, th { padding: 0 0.5em; text-align: right; }
+.swatch { display: inline-block; width: 1.5em; height: 1.5em; border: 1px solid #555; }
+</style>
+</head>
+<body>
+<section><h3>Frame</h3><img id="frame" width="512" height="480"></section>
+<section><h3>Nametables</h3><img id="nametables" width="512" height="480"></section>
+<section>
+<h3>Pattern tables</h3>
+<img id="patterns" width="512" height="256"><br>
+<label>palette <select id="palette"><option value="">default</option></select></label>
+<label><input type="checkbox" id="tall"> 8x16</label>
+</section>
+<section><h3>Palette</h3><div id="colors"></div></section>
+<section><h3>State</h3><pre id="state"></pre></section>
+<section><h3>OAM</h3><table id="oam"></table></section>
+<script>
+for (let i = 0; i < 8; i++) {
+  document.getElementById("palette").add(new Option(i < 4 ? "background " + i : "sprite " + (i - 4), i));
+}
+
+function image(id, query) {
+  const img = document.getElementById(id);
+  const next = new Image();
+  next.onload = () => { img.src = next.src; };
+  next.src = "/" + id + ".png?" + (query || "") + "&t=" + Date.now();
+}
+
+async function get(path) {
+  const response = await fetch(path, {cache: "no-store"});
+  if (!response.ok) throw new Error(await response.text());
+  return response.json();
+}
+
+async function poll() {
+  try {
+    image("frame");
+    image("nametables");
+    image("patterns", "palette=" + document.getElementById("palette").value +
+      (document.getElementById("tall").checked ? "&8x16=1" : ""));
+
+    const [state, palette, oam] = await Promise.all([get("/state.json"), get("/palette.json"), get("/oam.json")]);
+    const hex = (v, n) => v.toString(16).toUpperCase().padStart(n, "0");
+    const r = state.Registers;
+
+    document.getElementById("state").textContent =
+      "frame    " + state.Frame + "\n" +
+      "scanline " + state.Scanline + " cycle " + state.Cycle + "\n" +
+      "$2000    " + hex(r.Controller, 2) + "\n" +
+      "$2001    " + hex(r.Mask, 2) + "\n" +
+      "$2002    " + hex(r.Status, 2) + "\n" +
+      "$2003    " + hex(r.OAMAddress, 2) + "\n" +
+      "v        " + hex(r.Address, 4) + "\n" +
+      "scroll   " + state.ScrollX + "," + state.ScrollY;
+
+    document.getElementById("colors").innerHTML = palette.map((p, i) =>
+      (i % 4 == 0 && i ? "<br>" : "") +
+      '<span class="swatch" title="$' + hex(p.address, 4) + " = " + hex(p.index, 2) + '" style="background:' + p.color + '"></span>').join("");
+
+    document.getElementById("oam").innerHTML =
+      "<tr><th>#</th><th>X</th><th>Y</th><th>tile</th><th>attr</th></tr>" +
+      oam.filter(s => s.y < 0xef).map(s =>
+        "<tr><td>" + s.index + "</td><td>" + s.x + "</td><td>" + s.y + "</td><td>" + hex(s.tile, 2) +
+        "</td><td>" + hex(s.attributes, 2) + "</td></tr>").join("");
+  } catch (e) {
+    document.getElementById("state").textContent = e.message;
+  }
+
+  setTimeout(poll, 500);
+}
+
+poll();
+</script>
+</body>
+</html>
+`
+
+func (s *Server) dashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
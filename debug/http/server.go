@@ -0,0 +1,257 @@
+// Package http serves the state of a running RP2C02 over HTTP for
+// debugging: the current frame, pattern tables and nametables as PNG,
+// OAM, palette and register state as JSON, and an HTML dashboard that
+// polls them.
+//
+// The server observes the PPU and takes a snapshot every few completed
+// frames on the PPU's goroutine, so requests never touch the PPU while
+// it runs.
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/nwidger/rp2cgo2"
+	"github.com/nwidger/rp2cgo2/nametable"
+)
+
+// DefaultAddr only listens on the loopback interface.
+const DefaultAddr = "localhost:6502"
+
+type snapshot struct {
+	state  rp2cgo2.State
+	frame  rp2cgo2.Frame
+	memory rp2cgo2.Memory
+}
+
+// Server is an Observer of an RP2C02 and an http.Handler serving its
+// snapshots.  Every is the number of frames between snapshots.
+type Server struct {
+	rp2cgo2.BaseObserver
+	Every    int
+	ppu      *rp2cgo2.RP2C02
+	mux      *http.ServeMux
+	frames   int
+	lock     sync.RWMutex
+	snapshot *snapshot
+}
+
+// NewServer creates a Server observing ppu.  It must be called before
+// the PPU is started.
+func NewServer(ppu *rp2cgo2.RP2C02) *Server {
+	s := &Server{
+		Every: 6,
+		ppu:   ppu,
+		mux:   http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/", s.dashboard)
+	s.mux.HandleFunc("/frame.png", s.image(s.frame))
+	s.mux.HandleFunc("/patterns.png", s.image(s.patterns))
+	s.mux.HandleFunc("/nametables.png", s.image(s.nametables))
+	s.mux.HandleFunc("/oam.json", s.json(s.oam))
+	s.mux.HandleFunc("/palette.json", s.json(s.palette))
+	s.mux.HandleFunc("/state.json", s.json(s.state))
+
+	ppu.Observe(s)
+
+	return s
+}
+
+// ListenAndServe serves ppu's state on addr until an error occurs.
+func ListenAndServe(addr string, ppu *rp2cgo2.RP2C02) error {
+	return http.ListenAndServe(addr, NewServer(ppu))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// OnFrameComplete takes a snapshot of the PPU every Every frames.  VRAM
+// is copied with Memory.CopyTo, so mirrored nametables appear as the PPU
+// addresses them.
+func (s *Server) OnFrameComplete(frame *rp2cgo2.Frame) {
+	if s.frames++; s.frames < s.Every {
+		return
+	}
+
+	s.frames = 0
+
+	snap := &snapshot{
+		state: s.ppu.State(),
+		frame: rp2cgo2.Frame{Number: frame.Number, Pixels: append([]uint8{}, frame.Pixels...)},
+	}
+
+	s.ppu.Memory.CopyTo(&snap.memory)
+
+	s.lock.Lock()
+	s.snapshot = snap
+	s.lock.Unlock()
+}
+
+func (s *Server) latest(w http.ResponseWriter) (snap *snapshot) {
+	s.lock.RLock()
+	snap = s.snapshot
+	s.lock.RUnlock()
+
+	if snap == nil {
+		http.Error(w, "no frame has completed yet", http.StatusServiceUnavailable)
+	}
+
+	return
+}
+
+func (s *Server) image(render func(snap *snapshot, r *http.Request) (image.Image, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snap := s.latest(w)
+
+		if snap == nil {
+			return
+		}
+
+		img, err := render(snap, r)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-store")
+		png.Encode(w, img)
+	}
+}
+
+func (s *Server) json(render func(snap *snapshot) interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snap := s.latest(w)
+
+		if snap == nil {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(render(snap))
+	}
+}
+
+func (s *Server) frame(snap *snapshot, r *http.Request) (image.Image, error) {
+	return snap.frame.Image(s.ppu.Palette, &rp2cgo2.ScreenshotOptions{
+		CropOverscan: r.FormValue("overscan") != "",
+	}), nil
+}
+
+// patterns draws both pattern tables side by side.  The palette
+// parameter selects one of the eight palettes in palette RAM, 0-3 for
+// the background and 4-7 for sprites, in place of the default colors.
+func (s *Server) patterns(snap *snapshot, r *http.Request) (image.Image, error) {
+	colors := rp2cgo2.PatternTableColors
+
+	if value := r.FormValue("palette"); value != "" {
+		index, err := strconv.Atoi(value)
+
+		if err != nil || index < 0 || index > 7 {
+			return nil, fmt.Errorf("palette must be 0-7 not %q", value)
+		}
+
+		for i := range colors {
+			colors[i] = s.ppu.Palette.RGBA(snap.state.Palette[index*4+i])
+		}
+	}
+
+	left, right := snap.memory.DumpPatternTables(colors, r.FormValue("8x16") != "")
+
+	img := image.NewRGBA(image.Rect(0, 0, 256, 128))
+	draw.Draw(img, left.Bounds(), left, image.Point{}, draw.Src)
+	draw.Draw(img, right.Bounds().Add(image.Pt(128, 0)), right, image.Point{}, draw.Src)
+
+	return img, nil
+}
+
+func (s *Server) nametables(snap *snapshot, r *http.Request) (image.Image, error) {
+	base := uint16(0x0000)
+
+	if snap.state.Registers.Controller&0x10 != 0 {
+		base = 0x1000
+	}
+
+	return nametable.Image(&snap.memory, s.ppu.Palette, base), nil
+}
+
+type sprite struct {
+	Index      int   `json:"index"`
+	Y          uint8 `json:"y"`
+	Tile       uint8 `json:"tile"`
+	Attributes uint8 `json:"attributes"`
+	X          uint8 `json:"x"`
+	Palette    uint8 `json:"palette"`
+	Behind     bool  `json:"behind"`
+	FlipH      bool  `json:"flipH"`
+	FlipV      bool  `json:"flipV"`
+}
+
+func (s *Server) oam(snap *snapshot) interface{} {
+	sprites := make([]sprite, 64)
+
+	for i := range sprites {
+		data := snap.state.OAM[i*4 : i*4+4]
+
+		sprites[i] = sprite{
+			Index:      i,
+			Y:          data[0],
+			Tile:       data[1],
+			Attributes: data[2],
+			X:          data[3],
+			Palette:    data[2] & 0x03,
+			Behind:     data[2]&0x20 != 0,
+			FlipH:      data[2]&0x40 != 0,
+			FlipV:      data[2]&0x80 != 0,
+		}
+	}
+
+	return sprites
+}
+
+type paletteEntry struct {
+	Address uint16 `json:"address"`
+	Index   uint8  `json:"index"`
+	Color   string `json:"color"`
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func (s *Server) palette(snap *snapshot) interface{} {
+	entries := make([]paletteEntry, len(snap.state.Palette))
+
+	for i, index := range snap.state.Palette {
+		entries[i] = paletteEntry{
+			Address: 0x3f00 | uint16(i),
+			Index:   index,
+			Color:   hex(s.ppu.Palette.RGBA(index)),
+		}
+	}
+
+	return entries
+}
+
+func (s *Server) state(snap *snapshot) interface{} {
+	return struct {
+		rp2cgo2.State
+		ScrollX uint16
+		ScrollY uint16
+	}{snap.state, snap.state.Scroll.X(), snap.state.Scroll.Y()}
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+func get(t *testing.T, handler http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+	return w
+}
+
+func TestServer(t *testing.T) {
+	ppu := rp2cgo2.NewRP2C02(nil)
+	s := NewServer(ppu)
+	s.Every = 2
+
+	if w := get(t, s, "/frame.png"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Frame before a snapshot returned %d not 503", w.Code)
+	}
+
+	ppu.Memory.Store(0x3f01, 0x16)
+	ppu.Registers.Controller = 0x10
+	ppu.RenderFrame()
+
+	if w := get(t, s, "/state.json"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("State after one frame returned %d not 503", w.Code)
+	}
+
+	ppu.RenderFrame()
+
+	for _, test := range []struct {
+		path        string
+		contentType string
+	}{
+		{"/", "text/html; charset=utf-8"},
+		{"/frame.png", "image/png"},
+		{"/frame.png?overscan=1", "image/png"},
+		{"/patterns.png", "image/png"},
+		{"/patterns.png?palette=4&8x16=1", "image/png"},
+		{"/nametables.png", "image/png"},
+		{"/oam.json", "application/json"},
+		{"/palette.json", "application/json"},
+		{"/state.json", "application/json"},
+	} {
+		w := get(t, s, test.path)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s returned %d not 200", test.path, w.Code)
+		}
+
+		if contentType := w.Header().Get("Content-Type"); contentType != test.contentType {
+			t.Errorf("%s has content type %s not %s", test.path, contentType, test.contentType)
+		}
+	}
+
+	if w := get(t, s, "/patterns.png?palette=8"); w.Code != http.StatusBadRequest {
+		t.Errorf("Palette 8 returned %d not 400", w.Code)
+	}
+
+	if w := get(t, s, "/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("Missing page returned %d not 404", w.Code)
+	}
+
+	palette := []paletteEntry{}
+
+	if err := json.NewDecoder(get(t, s, "/palette.json").Body).Decode(&palette); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(palette) != 32 || palette[1].Index != 0x16 || palette[1].Color != "#982220" {
+		t.Errorf("Palette entry 1 is %+v", palette[1])
+	}
+
+	state := struct{ Frame uint16 }{}
+
+	if err := json.NewDecoder(get(t, s, "/state.json").Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	if state.Frame != 1 {
+		t.Errorf("State is from frame %d not 1", state.Frame)
+	}
+}
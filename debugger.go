@@ -0,0 +1,204 @@
+package rp2cgo2
+
+type BreakCondition uint8
+
+const (
+	BreakDot BreakCondition = iota
+	BreakVRAMWrite
+	BreakRegisterRead
+	BreakRegisterWrite
+	BreakSprite0Hit
+	BreakVBlank
+)
+
+func (c BreakCondition) String() string {
+	switch c {
+	case BreakDot:
+		return "Dot"
+	case BreakVRAMWrite:
+		return "VRAMWrite"
+	case BreakRegisterRead:
+		return "RegisterRead"
+	case BreakRegisterWrite:
+		return "RegisterWrite"
+	case BreakSprite0Hit:
+		return "Sprite0Hit"
+	case BreakVBlank:
+		return "VBlank"
+	}
+
+	return "Unknown"
+}
+
+// Breakpoint describes when the debugger should stop the PPU.  Scanline
+// and Cycle are used by BreakDot, Low and High give the inclusive
+// address range used by BreakVRAMWrite, BreakRegisterRead and
+// BreakRegisterWrite.
+type Breakpoint struct {
+	Condition BreakCondition
+	Scanline  uint16
+	Cycle     uint16
+	Low       uint16
+	High      uint16
+}
+
+func (bp *Breakpoint) matches(condition BreakCondition, address uint16) bool {
+	if bp.Condition != condition {
+		return false
+	}
+
+	switch condition {
+	case BreakVRAMWrite, BreakRegisterRead, BreakRegisterWrite:
+		return address >= bp.Low && address <= bp.High
+	}
+
+	return true
+}
+
+type DebugCommand uint8
+
+const (
+	Continue DebugCommand = iota
+	Step
+)
+
+// State is a snapshot of the PPU taken when the debugger stops.
+// Breakpoint is nil when the stop was caused by single-stepping.
+type State struct {
+	Frame          uint16
+	Scanline       uint16
+	Cycle          uint16
+	Registers      Registers
+	Scroll         ScrollState
+	PatternAddress uint16
+	AttributeLatch uint8
+	Attributes     uint16
+	TilesLatch     uint16
+	TilesLow       uint16
+	TilesHigh      uint16
+	Sprites        [8]Sprite
+	OAM            [256]uint8
+	SecondaryOAM   [32]uint8
+	Palette        [32]uint8
+	Breakpoint     *Breakpoint
+}
+
+// Debugger pauses Execute when one of its breakpoints is hit.  The PPU
+// sends its State on Stopped and then blocks until a DebugCommand is
+// received on Commands.  Step stops again after the next dot, Continue
+// runs until the next breakpoint.
+type Debugger struct {
+	Breakpoints []Breakpoint
+	Stopped     chan State
+	Commands    chan DebugCommand
+	hit         *Breakpoint
+	stepping    bool
+}
+
+func NewDebugger() *Debugger {
+	return &Debugger{
+		Breakpoints: []Breakpoint{},
+		Stopped:     make(chan State),
+		Commands:    make(chan DebugCommand),
+	}
+}
+
+func (debugger *Debugger) AddBreakpoint(bp Breakpoint) {
+	debugger.Breakpoints = append(debugger.Breakpoints, bp)
+}
+
+func (debugger *Debugger) ClearBreakpoints() {
+	debugger.Breakpoints = debugger.Breakpoints[:0]
+}
+
+// stop records a copy of bp as the breakpoint hit, so that it stays
+// valid when Breakpoints is changed while the PPU is stopped.
+// armed reports whether the debugger may stop at the next dot, either
+// because a breakpoint is set or because it is single-stepping.
+func (debugger *Debugger) armed() bool {
+	return len(debugger.Breakpoints) > 0 || debugger.stepping
+}
+
+func (debugger *Debugger) stop(bp Breakpoint) {
+	debugger.hit = &bp
+}
+
+func (debugger *Debugger) trigger(condition BreakCondition, address uint16) {
+	if debugger.hit != nil {
+		return
+	}
+
+	for i := range debugger.Breakpoints {
+		if debugger.Breakpoints[i].matches(condition, address) {
+			debugger.stop(debugger.Breakpoints[i])
+			return
+		}
+	}
+}
+
+func (debugger *Debugger) dot(ppu *RP2C02) {
+	if debugger.hit == nil {
+		for _, bp := range debugger.Breakpoints {
+			if bp.Condition == BreakDot && bp.Scanline == ppu.scanline && bp.Cycle == ppu.cycle {
+				debugger.stop(bp)
+				break
+			}
+		}
+	}
+
+	if debugger.hit == nil && !debugger.stepping {
+		return
+	}
+
+	ppu.catchUp()
+
+	state := ppu.State()
+	state.Breakpoint = debugger.hit
+	debugger.hit = nil
+
+	debugger.Stopped <- state
+
+	switch <-debugger.Commands {
+	case Step:
+		debugger.stepping = true
+	case Continue:
+		debugger.stepping = false
+	}
+}
+
+func (ppu *RP2C02) breakOn(condition BreakCondition, address uint16) {
+	if ppu.Debugger != nil {
+		ppu.Debugger.trigger(condition, address)
+	}
+}
+
+func (ppu *RP2C02) State() (state State) {
+	state = State{
+		Frame:          ppu.frame,
+		Scanline:       ppu.scanline,
+		Cycle:          ppu.cycle,
+		Registers:      ppu.Registers,
+		Scroll:         ppu.ScrollState(),
+		PatternAddress: ppu.patternAddress,
+		AttributeLatch: ppu.attributeLatch,
+		Attributes:     ppu.attributes,
+		TilesLatch:     ppu.tilesLatch,
+		TilesLow:       ppu.tilesLow,
+		TilesHigh:      ppu.tilesHigh,
+		Sprites:        ppu.sprites,
+	}
+
+	for i := range state.OAM {
+		state.OAM[i] = ppu.oam.Fetch(uint16(i))
+	}
+
+	for i := range state.SecondaryOAM {
+		state.SecondaryOAM[i] = ppu.oam.Buffer.Fetch(uint16(i))
+	}
+
+	for i := range state.Palette {
+		state.Palette[i] = ppu.Memory.Fetch(0x3f00 | uint16(i))
+	}
+
+	return
+}
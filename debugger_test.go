@@ -0,0 +1,144 @@
+package rp2cgo2
+
+import "testing"
+
+func TestDebuggerDotBreakpoint(t *testing.T) {
+	ppu := NewRP2C02(nil)
+	ppu.Reset()
+
+	ppu.Debugger = NewDebugger()
+	ppu.Debugger.AddBreakpoint(Breakpoint{Condition: BreakDot, Scanline: 0, Cycle: 10})
+
+	done := make(chan bool)
+
+	go func() {
+		ppu.quota = 0xffff
+
+		for ppu.cycle = 0; ppu.cycle < 20; ppu.cycle++ {
+			ppu.Execute()
+		}
+
+		close(done)
+	}()
+
+	state := <-ppu.Debugger.Stopped
+
+	if state.Scanline != 0 || state.Cycle != 10 {
+		t.Errorf("Stopped at %d,%d not 0,10", state.Scanline, state.Cycle)
+	}
+
+	if state.Breakpoint == nil || state.Breakpoint.Condition != BreakDot {
+		t.Errorf("Breakpoint is %v", state.Breakpoint)
+	}
+
+	ppu.Debugger.Commands <- Step
+	state = <-ppu.Debugger.Stopped
+
+	if state.Scanline != 0 || state.Cycle != 11 {
+		t.Errorf("Stopped at %d,%d not 0,11", state.Scanline, state.Cycle)
+	}
+
+	if state.Breakpoint != nil {
+		t.Errorf("Breakpoint is %v not nil", state.Breakpoint)
+	}
+
+	ppu.Debugger.Commands <- Continue
+	<-done
+}
+
+func TestDebuggerClearBreakpoints(t *testing.T) {
+	ppu := NewRP2C02(nil)
+
+	ppu.Debugger = NewDebugger()
+	ppu.Debugger.AddBreakpoint(Breakpoint{Condition: BreakVRAMWrite, Low: 0x2000, High: 0x23ff})
+
+	ppu.Store(0x2006, 0x20)
+	ppu.Store(0x2006, 0x00)
+	ppu.Store(0x2007, 0xff)
+
+	ppu.Debugger.ClearBreakpoints()
+	ppu.Debugger.AddBreakpoint(Breakpoint{Condition: BreakVBlank})
+
+	if ppu.Debugger.hit == nil || ppu.Debugger.hit.Condition != BreakVRAMWrite || ppu.Debugger.hit.High != 0x23ff {
+		t.Errorf("Breakpoint hit is %v not the cleared VRAM write breakpoint", ppu.Debugger.hit)
+	}
+}
+
+func TestDebuggerVRAMWrite(t *testing.T) {
+	ppu := NewRP2C02(nil)
+
+	ppu.Debugger = NewDebugger()
+	ppu.Debugger.AddBreakpoint(Breakpoint{Condition: BreakVRAMWrite, Low: 0x2000, High: 0x23ff})
+
+	ppu.Store(0x2006, 0x24)
+	ppu.Store(0x2006, 0x00)
+	ppu.Store(0x2007, 0xff)
+
+	if ppu.Debugger.hit != nil {
+		t.Error("Breakpoint hit outside of range")
+	}
+
+	ppu.Store(0x2006, 0x23)
+	ppu.Store(0x2006, 0xff)
+	ppu.Store(0x2007, 0xff)
+
+	if ppu.Debugger.hit == nil || ppu.Debugger.hit.Condition != BreakVRAMWrite {
+		t.Error("Breakpoint not hit")
+	}
+}
+
+func TestDebuggerScanlineRenderer(t *testing.T) {
+	stops := map[Renderer][]State{}
+
+	for _, renderer := range []Renderer{DotRenderer, ScanlineRenderer} {
+		ppu := newScene(renderer)
+
+		ppu.Debugger = NewDebugger()
+		ppu.Debugger.AddBreakpoint(Breakpoint{Condition: BreakDot, Scanline: 10, Cycle: 100})
+		ppu.Debugger.AddBreakpoint(Breakpoint{Condition: BreakSprite0Hit})
+
+		done := make(chan bool)
+
+		go func() {
+			renderFrame(ppu, nil)
+			close(done)
+		}()
+
+	loop:
+		for {
+			select {
+			case state := <-ppu.Debugger.Stopped:
+				stops[renderer] = append(stops[renderer], state)
+				ppu.Debugger.Commands <- Continue
+			case <-done:
+				break loop
+			}
+		}
+	}
+
+	dot, scanline := stops[DotRenderer], stops[ScanlineRenderer]
+
+	if len(dot) != 2 {
+		t.Fatalf("Dot renderer stopped %d times not 2", len(dot))
+	}
+
+	if len(scanline) != len(dot) {
+		t.Fatalf("Scanline renderer stopped %d times not %d", len(scanline), len(dot))
+	}
+
+	if dot[0].Scanline != 10 || dot[0].Cycle != 100 {
+		t.Errorf("Stopped at %d,%d not 10,100", dot[0].Scanline, dot[0].Cycle)
+	}
+
+	for i := range dot {
+		if *scanline[i].Breakpoint != *dot[i].Breakpoint {
+			t.Errorf("Stop %d: Breakpoint is %v not %v", i, *scanline[i].Breakpoint, *dot[i].Breakpoint)
+		}
+
+		scanline[i].Breakpoint, dot[i].Breakpoint = nil, nil
+
+		if scanline[i] != dot[i] {
+			t.Errorf("Stop %d: Scanline renderer stopped at %d,%d not %d,%d or its state differs", i, scanline[i].Scanline, scanline[i].Cycle, dot[i].Scanline, dot[i].Cycle)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package rp2cgo2
+
+import (
+	"image"
+	"image/color"
+)
+
+type EventKind uint8
+
+const (
+	ReadEvent EventKind = iota
+	WriteEvent
+	NMIEvent
+	Sprite0HitEvent
+	SpriteOverflowEvent
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case ReadEvent:
+		return "Read"
+	case WriteEvent:
+		return "Write"
+	case NMIEvent:
+		return "NMI"
+	case Sprite0HitEvent:
+		return "Sprite0Hit"
+	case SpriteOverflowEvent:
+		return "SpriteOverflow"
+	}
+
+	return "Unknown"
+}
+
+type Event struct {
+	Kind     EventKind
+	Frame    uint16
+	Scanline uint16
+	Cycle    uint16
+	Address  uint16
+	Value    uint8
+}
+
+// EventLog records register accesses and PPU events as they happen.
+// Events for the frame being rendered accumulate in Current and are
+// moved to Last once the frame completes.
+type EventLog struct {
+	Current []Event
+	Last    []Event
+}
+
+func NewEventLog() *EventLog {
+	return &EventLog{
+		Current: []Event{},
+		Last:    []Event{},
+	}
+}
+
+func (log *EventLog) Clear() {
+	log.Current = log.Current[:0]
+	log.Last = log.Last[:0]
+}
+
+func (log *EventLog) record(event Event) {
+	log.Current = append(log.Current, event)
+}
+
+func (log *EventLog) endFrame() {
+	log.Last, log.Current = log.Current, log.Last[:0]
+}
+
+func eventColor(event Event) (c color.RGBA) {
+	switch event.Kind {
+	case ReadEvent, WriteEvent:
+		switch event.Address & 0x0007 {
+		case 0x0000:
+			c = color.RGBA{255, 128, 128, 255}
+		case 0x0001:
+			c = color.RGBA{128, 255, 128, 255}
+		case 0x0002:
+			c = color.RGBA{255, 255, 0, 255}
+		case 0x0003:
+			c = color.RGBA{255, 128, 255, 255}
+		case 0x0004:
+			c = color.RGBA{255, 160, 64, 255}
+		case 0x0005:
+			c = color.RGBA{128, 255, 255, 255}
+		case 0x0006:
+			c = color.RGBA{128, 128, 255, 255}
+		case 0x0007:
+			c = color.RGBA{224, 224, 224, 255}
+		}
+
+		if event.Kind == ReadEvent {
+			c.R, c.G, c.B = c.R/2, c.G/2, c.B/2
+		}
+	case NMIEvent:
+		c = color.RGBA{255, 0, 0, 255}
+	case Sprite0HitEvent:
+		c = color.RGBA{0, 255, 0, 255}
+	case SpriteOverflowEvent:
+		c = color.RGBA{0, 128, 255, 255}
+	}
+
+	return
+}
+
+// Image plots the events of the last completed frame onto a grid with
+// one pixel per dot, columns being cycles and rows being scanlines.
+func (log *EventLog) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, int(CYCLES_PER_SCANLINE), NUM_SCANLINES))
+
+	for y := 0; y < NUM_SCANLINES; y++ {
+		for x := 0; x < int(CYCLES_PER_SCANLINE); x++ {
+			c := color.RGBA{16, 16, 16, 255}
+
+			switch {
+			case y < 240 && x >= 1 && x <= 256:
+				c = color.RGBA{48, 48, 48, 255}
+			case y >= VBLANK_SCANLINE && y < NUM_SCANLINES-1:
+				c = color.RGBA{32, 32, 64, 255}
+			}
+
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	for _, event := range log.Last {
+		img.SetRGBA(int(event.Cycle), int(event.Scanline), eventColor(event))
+	}
+
+	return img
+}
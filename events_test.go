@@ -0,0 +1,79 @@
+package rp2cgo2
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestEventLogRegisterAccess(t *testing.T) {
+	ppu := NewRP2C02(nil)
+	ppu.Events = NewEventLog()
+
+	ppu.scanline = 100
+	ppu.cycle = 200
+	ppu.Store(0x2001, 0x1e)
+
+	ppu.scanline = 101
+	ppu.cycle = 5
+	ppu.Fetch(0x2002)
+
+	if len(ppu.Events.Current) != 2 {
+		t.Fatalf("Log has %d events not 2", len(ppu.Events.Current))
+	}
+
+	event := ppu.Events.Current[0]
+
+	if event.Kind != WriteEvent || event.Address != 0x2001 || event.Value != 0x1e ||
+		event.Scanline != 100 || event.Cycle != 200 {
+		t.Errorf("Event is %+v", event)
+	}
+
+	event = ppu.Events.Current[1]
+
+	if event.Kind != ReadEvent || event.Address != 0x2002 ||
+		event.Scanline != 101 || event.Cycle != 5 {
+		t.Errorf("Event is %+v", event)
+	}
+
+	ppu.Events.endFrame()
+
+	if len(ppu.Events.Current) != 0 {
+		t.Errorf("Log has %d current events not 0", len(ppu.Events.Current))
+	}
+
+	if len(ppu.Events.Last) != 2 {
+		t.Errorf("Log has %d last events not 2", len(ppu.Events.Last))
+	}
+}
+
+func TestEventLogImage(t *testing.T) {
+	log := NewEventLog()
+
+	log.record(Event{Kind: WriteEvent, Scanline: 10, Cycle: 20, Address: 0x2005})
+	log.endFrame()
+
+	img := log.Image()
+
+	if img.Bounds().Dx() != int(CYCLES_PER_SCANLINE) || img.Bounds().Dy() != NUM_SCANLINES {
+		t.Errorf("Image is %v not 341x262", img.Bounds())
+	}
+
+	if img.RGBAAt(20, 10) != eventColor(log.Last[0]) {
+		t.Errorf("Pixel is %v not %v", img.RGBAAt(20, 10), eventColor(log.Last[0]))
+	}
+
+	for _, test := range []struct {
+		x, y  int
+		color color.RGBA
+	}{
+		{100, 10, color.RGBA{48, 48, 48, 255}},
+		{300, 10, color.RGBA{16, 16, 16, 255}},
+		{300, 240, color.RGBA{16, 16, 16, 255}},
+		{300, 241, color.RGBA{32, 32, 64, 255}},
+		{300, 261, color.RGBA{16, 16, 16, 255}},
+	} {
+		if c := img.RGBAAt(test.x, test.y); c != test.color {
+			t.Errorf("Pixel %d,%d is %v not %v", test.x, test.y, c, test.color)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package filter
+
+import "image"
+
+// CRT is a software post-process that makes RGB frames look as though
+// they were shown on a CRT.  Each source pixel is scaled up to a Scale
+// by Scale block, the last row of which is darkened to leave a gap
+// between scanlines, and the output's columns are tinted red, green and
+// blue in turn like an aperture grille whatever the Scale.  Bright
+// pixels bloom into their neighbours and phosphors fade out over
+// several frames rather than going dark at once.
+//
+// Strengths are between 0 (off) and 1.  A CRT keeps the previous frame
+// for persistence so must not be shared between streams of frames.
+type CRT struct {
+	Scale       int
+	Scanline    float64
+	Mask        float64
+	Bloom       float64
+	Persistence float64
+
+	phosphor []uint8
+	glow     []uint8
+	weights  [][3]uint16
+	settings [5]float64
+}
+
+func NewCRT() *CRT {
+	return &CRT{
+		Scale:       3,
+		Scanline:    0.35,
+		Mask:        0.2,
+		Bloom:       0.25,
+		Persistence: 0.3,
+	}
+}
+
+func fixed(strength float64) uint16 {
+	switch {
+	case strength < 0:
+		return 0
+	case strength > 1:
+		return 256
+	}
+
+	return uint16(strength*256 + 0.5)
+}
+
+// prepare computes the weight of each channel for each row within a
+// Scale by Scale block and each output column modulo 3, as a fraction of
+// 256.
+func (crt *CRT) prepare() {
+	settings := [5]float64{float64(crt.Scale), crt.Scanline, crt.Mask, crt.Bloom, crt.Persistence}
+
+	if crt.weights != nil && settings == crt.settings {
+		return
+	}
+
+	crt.settings = settings
+	crt.weights = make([][3]uint16, crt.Scale*3)
+
+	scanline, mask := 256-fixed(crt.Scanline), 256-fixed(crt.Mask)
+
+	for y := 0; y < crt.Scale; y++ {
+		row := uint16(256)
+
+		if y == crt.Scale-1 && crt.Scale > 1 {
+			row = scanline
+		}
+
+		for x := 0; x < 3; x++ {
+			for c := range crt.weights[y*3+x] {
+				weight := row
+
+				if c != x {
+					weight = uint16(uint32(weight) * uint32(mask) >> 8)
+				}
+
+				crt.weights[y*3+x][c] = weight
+			}
+		}
+	}
+}
+
+// Reset forgets the previous frame so the next one starts with no
+// phosphor persistence.
+func (crt *CRT) Reset() {
+	crt.phosphor = crt.phosphor[:0]
+}
+
+// Apply processes src, writing the result into dst if it is the right
+// size and returning it.
+func (crt *CRT) Apply(dst, src *image.RGBA) *image.RGBA {
+	if crt.Scale < 1 {
+		crt.Scale = 1
+	}
+
+	crt.prepare()
+
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	scale := crt.Scale
+	dst = rgba(dst, width*scale, height*scale)
+	size := width * height * 4
+
+	if len(crt.phosphor) != size {
+		crt.phosphor = make([]uint8, size)
+		crt.glow = make([]uint8, size)
+	}
+
+	// phosphors glow with whichever is brighter of the new frame and
+	// the previous glow faded by Persistence
+	persistence := fixed(crt.Persistence)
+
+	for y := 0; y < height; y++ {
+		row := src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y):]
+		phosphor := crt.phosphor[y*width*4 : (y+1)*width*4]
+
+		for i := range phosphor {
+			if i&3 == 3 {
+				phosphor[i] = 0xff
+				continue
+			}
+
+			faded := uint8(uint16(phosphor[i]) * persistence >> 8)
+
+			if row[i] > faded {
+				faded = row[i]
+			}
+
+			phosphor[i] = faded
+		}
+	}
+
+	// bloom is a 1-2-1 blur of each row, added on top of the glow
+	bloom := fixed(crt.Bloom)
+
+	for y := 0; y < height; y++ {
+		phosphor := crt.phosphor[y*width*4 : (y+1)*width*4]
+		glow := crt.glow[y*width*4 : (y+1)*width*4]
+
+		for x := 0; x < width; x++ {
+			left, right := x-1, x+1
+
+			if left < 0 {
+				left = 0
+			}
+
+			if right >= width {
+				right = width - 1
+			}
+
+			for c := 0; c < 3; c++ {
+				blur := (uint16(phosphor[left*4+c]) + uint16(phosphor[x*4+c])*2 + uint16(phosphor[right*4+c])) >> 2
+				value := uint16(phosphor[x*4+c]) + blur*bloom>>8
+
+				if value > 0xff {
+					value = 0xff
+				}
+
+				glow[x*4+c] = uint8(value)
+			}
+
+			glow[x*4+3] = 0xff
+		}
+	}
+
+	for y := 0; y < height*scale; y++ {
+		glow := crt.glow[y/scale*width*4 : (y/scale+1)*width*4]
+		weights := crt.weights[y%scale*3 : (y%scale+1)*3]
+		out := dst.Pix[y*dst.Stride : y*dst.Stride+width*scale*4]
+
+		// column is the output column modulo 3, which picks the
+		// grille's channel
+		for x, i, column := 0, 0, 0; x < width; x++ {
+			r, g, b := uint16(glow[x*4]), uint16(glow[x*4+1]), uint16(glow[x*4+2])
+
+			for k := 0; k < scale; k++ {
+				w := &weights[column]
+				out[i] = uint8(r * w[0] >> 8)
+				out[i+1] = uint8(g * w[1] >> 8)
+				out[i+2] = uint8(b * w[2] >> 8)
+				out[i+3] = 0xff
+				i += 4
+
+				if column++; column == 3 {
+					column = 0
+				}
+			}
+		}
+	}
+
+	return dst
+}
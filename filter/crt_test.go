@@ -0,0 +1,139 @@
+package filter
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func solid(c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	return img
+}
+
+func TestCRT(t *testing.T) {
+	crt := NewCRT()
+	crt.Bloom = 0
+	crt.Persistence = 0.5
+
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	dst := crt.Apply(nil, solid(white))
+
+	if size := dst.Rect.Size(); size.X != 12 || size.Y != 12 {
+		t.Fatalf("CRT produced %v image not 12x12", size)
+	}
+
+	for _, test := range []struct {
+		x, y  int
+		color color.RGBA
+	}{
+		{0, 0, color.RGBA{0xff, 0xcc, 0xcc, 0xff}},
+		{1, 0, color.RGBA{0xcc, 0xff, 0xcc, 0xff}},
+		{2, 0, color.RGBA{0xcc, 0xcc, 0xff, 0xff}},
+		{0, 2, color.RGBA{0xa5, 0x83, 0x83, 0xff}},
+		{4, 7, color.RGBA{0xcc, 0xff, 0xcc, 0xff}},
+	} {
+		if c := dst.RGBAAt(test.x, test.y); c != test.color {
+			t.Errorf("Pixel %d,%d is %v not %v", test.x, test.y, c, test.color)
+		}
+	}
+
+	if crt.Apply(dst, solid(color.RGBA{0, 0, 0, 0xff})) != dst {
+		t.Error("CRT did not reuse its destination")
+	}
+
+	if c := dst.RGBAAt(0, 0); c.R != 0x7f {
+		t.Errorf("Persistence left red at %02X not 0x7f", c.R)
+	}
+
+	crt.Reset()
+	crt.Apply(dst, solid(color.RGBA{0, 0, 0, 0xff}))
+
+	if c := dst.RGBAAt(0, 0); c.R != 0 {
+		t.Errorf("Reset left red at %02X not 0x00", c.R)
+	}
+}
+
+func TestCRTMask(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 6, 2))
+
+	for i := range white.Pix {
+		white.Pix[i] = 0xff
+	}
+
+	for _, scale := range []int{2, 4} {
+		crt := NewCRT()
+		crt.Scale = scale
+		crt.Bloom = 0
+
+		dst := crt.Apply(nil, white)
+		sums := [3]int{}
+
+		// every channel is lit on a third of the columns of a row
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			c := dst.RGBAAt(x, 0)
+			sums[0] += int(c.R)
+			sums[1] += int(c.G)
+			sums[2] += int(c.B)
+		}
+
+		if sums[0] != sums[1] || sums[1] != sums[2] {
+			t.Errorf("Scale %d channel averages are %v", scale, [3]float64{
+				float64(sums[0]) / float64(dst.Rect.Dx()),
+				float64(sums[1]) / float64(dst.Rect.Dx()),
+				float64(sums[2]) / float64(dst.Rect.Dx()),
+			})
+		}
+
+		if c := dst.RGBAAt(3, 0); c != (color.RGBA{0xff, 0xcc, 0xcc, 0xff}) {
+			t.Errorf("Scale %d pixel 3,0 is %v not red", scale, c)
+		}
+	}
+}
+
+func TestCRTBloom(t *testing.T) {
+	crt := NewCRT()
+	crt.Scale = 1
+	crt.Bloom = 1
+
+	src := solid(color.RGBA{0, 0, 0, 0xff})
+	src.SetRGBA(1, 1, color.RGBA{0xff, 0xff, 0xff, 0xff})
+
+	dst := crt.Apply(nil, src)
+
+	if c := dst.RGBAAt(2, 1); c.R == 0 || c.R >= 0xff {
+		t.Errorf("Bloom next to a white pixel is %02X", c.R)
+	}
+
+	if c := dst.RGBAAt(2, 2); c.R != 0 {
+		t.Errorf("Bloom below a white pixel is %02X not 0x00", c.R)
+	}
+}
+
+func BenchmarkCRT(b *testing.B) {
+	src := image.NewRGBA(image.Rect(0, 0, 256, 240))
+
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 7)
+	}
+
+	crt := NewCRT()
+	dst := crt.Apply(nil, src)
+	start := time.Now()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		dst = crt.Apply(dst, src)
+	}
+
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "frames/s")
+}
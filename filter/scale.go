@@ -0,0 +1,159 @@
+// Package filter upscales the palette index frames produced by the PPU.
+// Filters compare palette indices rather than colors, which is both
+// cheaper and exact since equal colors always share an index.
+//
+// Scale2x and Scale3x produce palette indices.  XBR2x and XBR3x blend
+// neighbouring colors and so produce RGB images.  Every filter returns
+// dst unchanged when width is not positive.
+//
+// There is no hqx filter.  hqx is defined by the reference
+// implementation's 256 case interpolation tables, and a port that has
+// not been checked against images made by that implementation would
+// only look like hqx.
+package filter
+
+import "image"
+
+// neighbours returns the 3x3 block of src around x, y, repeating the
+// edge pixels beyond the frame's borders.
+func neighbours(src []uint8, width, height, x, y int) (n [9]uint8) {
+	for dy := -1; dy <= 1; dy++ {
+		sy := clamp(y+dy, height)
+
+		for dx := -1; dx <= 1; dx++ {
+			n[(dy+1)*3+dx+1] = src[sy*width+clamp(x+dx, width)]
+		}
+	}
+
+	return
+}
+
+func clamp(v, size int) int {
+	switch {
+	case v < 0:
+		return 0
+	case v >= size:
+		return size - 1
+	}
+
+	return v
+}
+
+func grow(dst []uint8, size int) []uint8 {
+	if cap(dst) < size {
+		return make([]uint8, size)
+	}
+
+	return dst[:size]
+}
+
+func rgba(dst *image.RGBA, width, height int) *image.RGBA {
+	if dst == nil || dst.Rect.Dx() != width || dst.Rect.Dy() != height {
+		return image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+
+	return dst
+}
+
+// Scale2x doubles the size of the width pixel wide frame src using the
+// Scale2x (EPX) algorithm, writing the result into dst, which is grown
+// if needed, and returning it.
+func Scale2x(dst, src []uint8, width int) []uint8 {
+	if width <= 0 {
+		return dst
+	}
+
+	height := len(src) / width
+	dst = grow(dst, len(src)*4)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			n := neighbours(src, width, height, x, y)
+			b, d, e, f, h := n[1], n[3], n[4], n[5], n[7]
+			e0, e1, e2, e3 := e, e, e, e
+
+			if b != h && d != f {
+				if d == b {
+					e0 = d
+				}
+
+				if b == f {
+					e1 = f
+				}
+
+				if d == h {
+					e2 = d
+				}
+
+				if h == f {
+					e3 = f
+				}
+			}
+
+			i := y*2*width*2 + x*2
+			dst[i], dst[i+1] = e0, e1
+			dst[i+width*2], dst[i+width*2+1] = e2, e3
+		}
+	}
+
+	return dst
+}
+
+// Scale3x triples the size of src using the Scale3x (AdvMAME3x)
+// algorithm.
+func Scale3x(dst, src []uint8, width int) []uint8 {
+	if width <= 0 {
+		return dst
+	}
+
+	height := len(src) / width
+	dst = grow(dst, len(src)*9)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			n := neighbours(src, width, height, x, y)
+			a, b, c, d, e, f, g, h, i := n[0], n[1], n[2], n[3], n[4], n[5], n[6], n[7], n[8]
+			out := [9]uint8{e, e, e, e, e, e, e, e, e}
+
+			if b != h && d != f {
+				if d == b {
+					out[0] = d
+				}
+
+				if (d == b && e != c) || (b == f && e != a) {
+					out[1] = b
+				}
+
+				if b == f {
+					out[2] = f
+				}
+
+				if (d == b && e != g) || (d == h && e != a) {
+					out[3] = d
+				}
+
+				if (b == f && e != i) || (h == f && e != c) {
+					out[5] = f
+				}
+
+				if d == h {
+					out[6] = d
+				}
+
+				if (d == h && e != i) || (h == f && e != g) {
+					out[7] = h
+				}
+
+				if h == f {
+					out[8] = f
+				}
+			}
+
+			for row := 0; row < 3; row++ {
+				copy(dst[(y*3+row)*width*3+x*3:], out[row*3:row*3+3])
+			}
+		}
+	}
+
+	return dst
+}
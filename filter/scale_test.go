@@ -0,0 +1,106 @@
+package filter
+
+import (
+	"image"
+	"testing"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+// diagonal is a 4x4 frame with a staircase edge between indices 1 and 2.
+var diagonal = []uint8{
+	1, 1, 1, 2,
+	1, 1, 2, 2,
+	1, 2, 2, 2,
+	2, 2, 2, 2,
+}
+
+func TestScale2x(t *testing.T) {
+	dst := Scale2x(nil, diagonal, 4)
+
+	if len(dst) != 64 {
+		t.Fatalf("Scale2x produced %d pixels not 64", len(dst))
+	}
+
+	// pixel 1,1 is index 1 with 2 below and to the right, so its
+	// bottom right quarter follows the edge
+	for _, test := range []struct {
+		x, y  int
+		index uint8
+	}{
+		{2, 2, 1}, {3, 2, 1}, {2, 3, 1}, {3, 3, 2},
+		{0, 0, 1}, {7, 7, 2},
+	} {
+		if index := dst[test.y*8+test.x]; index != test.index {
+			t.Errorf("Pixel %d,%d is %02X not %02X", test.x, test.y, index, test.index)
+		}
+	}
+
+	flat := make([]uint8, 16)
+
+	for i := range flat {
+		flat[i] = 0x0f
+	}
+
+	for i, index := range Scale2x(dst, flat, 4) {
+		if index != 0x0f {
+			t.Errorf("Flat pixel %d is %02X not 0x0f", i, index)
+		}
+	}
+}
+
+func TestScale3x(t *testing.T) {
+	dst := Scale3x(nil, diagonal, 4)
+
+	if len(dst) != 144 {
+		t.Fatalf("Scale3x produced %d pixels not 144", len(dst))
+	}
+
+	for _, test := range []struct {
+		x, y  int
+		index uint8
+	}{
+		{3, 3, 1}, {4, 4, 1}, {5, 5, 2}, {5, 4, 1}, {4, 5, 1},
+		{0, 0, 1}, {11, 11, 2},
+	} {
+		if index := dst[test.y*12+test.x]; index != test.index {
+			t.Errorf("Pixel %d,%d is %02X not %02X", test.x, test.y, index, test.index)
+		}
+	}
+}
+
+func BenchmarkScale2x(b *testing.B) {
+	src := make([]uint8, 256*240)
+	dst := []uint8{}
+
+	for i := range src {
+		src[i] = uint8(i/7+i/256) & 0x3f
+	}
+
+	for i := 0; i < b.N; i++ {
+		dst = Scale2x(dst, src, 256)
+	}
+}
+
+func TestZeroWidth(t *testing.T) {
+	dst := []uint8{1, 2, 3}
+
+	if out := Scale2x(dst, diagonal, 0); len(out) != 3 {
+		t.Errorf("Scale2x with zero width returned %d pixels not 3", len(out))
+	}
+
+	if out := Scale3x(nil, diagonal, 0); out != nil {
+		t.Errorf("Scale3x with zero width returned %d pixels", len(out))
+	}
+
+	palette := &rp2cgo2.DefaultPalette
+
+	for name, filter := range map[string]func(*image.RGBA, []uint8, int, *rp2cgo2.Palette) *image.RGBA{
+		"XBR2x": XBR2x,
+		"XBR3x": XBR3x,
+	} {
+		if out := filter(nil, diagonal, 0, palette); out != nil {
+			t.Errorf("%s with zero width returned a %v image", name, out.Rect)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+func TestXBR2x(t *testing.T) {
+	palette := &rp2cgo2.DefaultPalette
+	one, two := palette.RGBA(1), palette.RGBA(2)
+	dst := XBR2x(nil, diagonal, 4, palette)
+
+	if size := dst.Rect.Size(); size.X != 8 || size.Y != 8 {
+		t.Fatalf("XBR2x produced %v image not 8x8", size)
+	}
+
+	for _, test := range []struct {
+		x, y  int
+		color color.RGBA
+	}{
+		{0, 0, one}, {2, 2, one}, {7, 7, two},
+		{3, 3, mix(one, two, 4)},
+		{4, 4, two},
+	} {
+		if c := dst.RGBAAt(test.x, test.y); c != test.color {
+			t.Errorf("Pixel %d,%d is %v not %v", test.x, test.y, c, test.color)
+		}
+	}
+}
+
+func TestXBR3x(t *testing.T) {
+	palette := &rp2cgo2.DefaultPalette
+	one, two := palette.RGBA(1), palette.RGBA(2)
+	dst := XBR3x(nil, diagonal, 4, palette)
+
+	if size := dst.Rect.Size(); size.X != 12 || size.Y != 12 {
+		t.Fatalf("XBR3x produced %v image not 12x12", size)
+	}
+
+	// pixel 1,1 is index 1 with 2 below and to the right, so its bottom
+	// right corner and the pixels either side of it follow the edge
+	for _, test := range []struct {
+		x, y  int
+		color color.RGBA
+	}{
+		{0, 0, one}, {3, 3, one}, {4, 4, one}, {11, 11, two},
+		{5, 5, mix(one, two, 7)},
+		{5, 4, mix(one, two, 2)},
+		{4, 5, mix(one, two, 2)},
+		{6, 6, two},
+	} {
+		if c := dst.RGBAAt(test.x, test.y); c != test.color {
+			t.Errorf("Pixel %d,%d is %v not %v", test.x, test.y, c, test.color)
+		}
+	}
+
+	if XBR3x(dst, diagonal, 4, palette) != dst {
+		t.Error("XBR3x did not reuse its destination")
+	}
+}
@@ -0,0 +1,104 @@
+package rp2cgo2
+
+import "sync/atomic"
+
+type Frame struct {
+	Number uint16
+	Pixels []uint8
+}
+
+const (
+	frameIndex uint32 = 0x03
+	frameFresh uint32 = 0x04
+)
+
+// FrameBuffer is a lock-free triple buffer used to hand completed frames
+// from the PPU to a frontend.  The PPU renders into the back buffer and
+// on completion swaps it with the middle buffer, never waiting on the
+// consumer.  Latest swaps the middle buffer into the front if a newer
+// frame is available, so the consumer always sees the most recently
+// completed frame.
+//
+// A frame published before the previous one was picked up is counted
+// as dropped, a call to Latest with no new frame available is counted
+// as a duplicate once a first frame has been returned.
+type FrameBuffer struct {
+	frames     [3]Frame
+	state      uint32
+	back       uint32
+	front      uint32
+	dropped    uint64
+	duplicated uint64
+}
+
+func NewFrameBuffer() *FrameBuffer {
+	return &FrameBuffer{
+		state: 1,
+		back:  0,
+		front: 2,
+	}
+}
+
+// pixels returns the back buffer's pixels, emptied, for the PPU to
+// render the next frame into.
+func (fb *FrameBuffer) pixels() []uint8 {
+	return fb.frames[fb.back].Pixels[:0]
+}
+
+func (fb *FrameBuffer) publish(number uint16, pixels []uint8) {
+	if pixels == nil {
+		pixels = []uint8{}
+	}
+
+	fb.frames[fb.back] = Frame{Number: number, Pixels: pixels}
+
+	for {
+		old := atomic.LoadUint32(&fb.state)
+
+		if atomic.CompareAndSwapUint32(&fb.state, old, fb.back|frameFresh) {
+			fb.back = old & frameIndex
+
+			if old&frameFresh != 0 {
+				atomic.AddUint64(&fb.dropped, 1)
+			}
+
+			return
+		}
+	}
+}
+
+// Latest returns the most recently completed frame, or nil if no frame
+// has completed yet.  The returned frame is only valid until the next
+// call to Latest.  Latest must only be called from a single goroutine.
+func (fb *FrameBuffer) Latest() *Frame {
+	for {
+		old := atomic.LoadUint32(&fb.state)
+
+		if old&frameFresh == 0 {
+			if fb.frames[fb.front].Pixels != nil {
+				atomic.AddUint64(&fb.duplicated, 1)
+			}
+
+			break
+		}
+
+		if atomic.CompareAndSwapUint32(&fb.state, old, fb.front) {
+			fb.front = old & frameIndex
+			break
+		}
+	}
+
+	if fb.frames[fb.front].Pixels == nil {
+		return nil
+	}
+
+	return &fb.frames[fb.front]
+}
+
+func (fb *FrameBuffer) Dropped() uint64 {
+	return atomic.LoadUint64(&fb.dropped)
+}
+
+func (fb *FrameBuffer) Duplicated() uint64 {
+	return atomic.LoadUint64(&fb.duplicated)
+}
@@ -0,0 +1,97 @@
+package rp2cgo2
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrameBuffer(t *testing.T) {
+	fb := NewFrameBuffer()
+
+	if frame := fb.Latest(); frame != nil {
+		t.Errorf("Frame is %v not nil", frame)
+	}
+
+	fb.publish(0, append(fb.pixels(), 0x00))
+	fb.publish(1, append(fb.pixels(), 0x01))
+
+	if fb.Dropped() != 1 {
+		t.Errorf("Dropped is %d not 1", fb.Dropped())
+	}
+
+	frame := fb.Latest()
+
+	if frame == nil || frame.Number != 1 || frame.Pixels[0] != 0x01 {
+		t.Fatalf("Frame is %v not frame 1", frame)
+	}
+
+	if frame = fb.Latest(); frame == nil || frame.Number != 1 {
+		t.Fatalf("Frame is %v not frame 1", frame)
+	}
+
+	if fb.Duplicated() != 1 {
+		t.Errorf("Duplicated is %d not 1", fb.Duplicated())
+	}
+
+	fb.publish(2, append(fb.pixels(), 0x02))
+
+	if frame = fb.Latest(); frame == nil || frame.Number != 2 || frame.Pixels[0] != 0x02 {
+		t.Fatalf("Frame is %v not frame 2", frame)
+	}
+
+	if fb.Dropped() != 1 || fb.Duplicated() != 1 {
+		t.Errorf("Dropped is %d and Duplicated is %d not 1 and 1", fb.Dropped(), fb.Duplicated())
+	}
+}
+
+func TestFrameBufferStartup(t *testing.T) {
+	fb := NewFrameBuffer()
+
+	for i := 0; i < 3; i++ {
+		fb.Latest()
+	}
+
+	if fb.Duplicated() != 0 {
+		t.Errorf("Duplicated is %d not 0 before the first frame", fb.Duplicated())
+	}
+
+	fb.publish(0, append(fb.pixels(), 0x00))
+	fb.Latest()
+	fb.Latest()
+
+	if fb.Duplicated() != 1 {
+		t.Errorf("Duplicated is %d not 1", fb.Duplicated())
+	}
+}
+
+func TestFrameBufferRun(t *testing.T) {
+	ppu := NewRP2C02WithConfig(Config{})
+	ppu.Registers.Mask = uint8(ShowBackground)
+
+	go ppu.Run()
+
+	go func() {
+		for {
+			ppu.Cycles <- 0xffff
+			<-ppu.Cycles
+		}
+	}()
+
+	timeout := time.After(10 * time.Second)
+
+	for {
+		select {
+		case <-timeout:
+			t.Fatal("Timed out waiting for frame 3")
+		case <-time.After(time.Millisecond):
+		}
+
+		if frame := ppu.Frames.Latest(); frame != nil && frame.Number >= 3 {
+			if len(frame.Pixels) != 256*240 {
+				t.Errorf("Frame has %d pixels not %d", len(frame.Pixels), 256*240)
+			}
+
+			break
+		}
+	}
+}
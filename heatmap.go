@@ -0,0 +1,100 @@
+package rp2cgo2
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+type AccessSource uint8
+
+const (
+	CPUAccess AccessSource = iota
+	RenderAccess
+)
+
+func (s AccessSource) String() string {
+	switch s {
+	case CPUAccess:
+		return "CPU"
+	case RenderAccess:
+		return "Render"
+	}
+
+	return "Unknown"
+}
+
+// AccessMap counts reads and writes of each VRAM address (0x0000 -
+// 0x3fff), split by whether the access came from the CPU through $2007
+// or from the rendering pipeline.  Rendering reads include the palette
+// read for every pixel output, the backdrop's while rendering is
+// disabled.  Counts for the frame being rendered
+// accumulate in Reads and Writes and are moved to LastReads and
+// LastWrites once the frame completes.
+type AccessMap struct {
+	Reads      [2][0x4000]uint32
+	Writes     [2][0x4000]uint32
+	LastReads  [2][0x4000]uint32
+	LastWrites [2][0x4000]uint32
+}
+
+func NewAccessMap() *AccessMap {
+	return &AccessMap{}
+}
+
+func (m *AccessMap) Clear() {
+	*m = AccessMap{}
+}
+
+func (m *AccessMap) read(source AccessSource, address uint16) {
+	m.Reads[source][address&0x3fff]++
+}
+
+func (m *AccessMap) write(source AccessSource, address uint16) {
+	m.Writes[source][address&0x3fff]++
+}
+
+func (m *AccessMap) endFrame() {
+	m.LastReads = m.Reads
+	m.LastWrites = m.Writes
+	m.Reads = [2][0x4000]uint32{}
+	m.Writes = [2][0x4000]uint32{}
+}
+
+// Image renders the last completed frame's accesses from source as a
+// 128x128 heatmap with one pixel per address, 128 addresses per row.
+// Reads are drawn in green and writes in red, scaled logarithmically
+// against the busiest address.
+func (m *AccessMap) Image(source AccessSource) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 128, 128))
+
+	max := uint32(0)
+
+	for address := 0; address < 0x4000; address++ {
+		if m.LastReads[source][address] > max {
+			max = m.LastReads[source][address]
+		}
+
+		if m.LastWrites[source][address] > max {
+			max = m.LastWrites[source][address]
+		}
+	}
+
+	scale := func(count uint32) uint8 {
+		if count == 0 || max == 0 {
+			return 0
+		}
+
+		return uint8(64 + 191*math.Log1p(float64(count))/math.Log1p(float64(max)))
+	}
+
+	for address := 0; address < 0x4000; address++ {
+		img.SetRGBA(address&0x7f, address>>7, color.RGBA{
+			R: scale(m.LastWrites[source][address]),
+			G: scale(m.LastReads[source][address]),
+			A: 255,
+		})
+	}
+
+	return img
+}
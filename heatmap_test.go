@@ -0,0 +1,89 @@
+package rp2cgo2
+
+import "testing"
+
+func TestAccessMapCPU(t *testing.T) {
+	ppu := NewRP2C02(nil)
+	ppu.Accesses = NewAccessMap()
+
+	ppu.Store(0x2006, 0x21)
+	ppu.Store(0x2006, 0x00)
+	ppu.Store(0x2007, 0x01)
+	ppu.Store(0x2007, 0x02)
+
+	ppu.Store(0x2006, 0x21)
+	ppu.Store(0x2006, 0x00)
+	ppu.Fetch(0x2007)
+
+	if ppu.Accesses.Writes[CPUAccess][0x2100] != 1 || ppu.Accesses.Writes[CPUAccess][0x2101] != 1 {
+		t.Error("Writes are not counted")
+	}
+
+	if ppu.Accesses.Reads[CPUAccess][0x2100] != 1 {
+		t.Error("Reads are not counted")
+	}
+
+	if ppu.Accesses.Reads[RenderAccess][0x2100] != 0 {
+		t.Error("CPU reads are counted as rendering reads")
+	}
+
+	ppu.Accesses.endFrame()
+
+	if ppu.Accesses.Writes[CPUAccess][0x2100] != 0 || ppu.Accesses.LastWrites[CPUAccess][0x2100] != 1 {
+		t.Error("Counts are not moved to the last frame")
+	}
+
+	img := ppu.Accesses.Image(CPUAccess)
+
+	if c := img.RGBAAt(0x2100&0x7f, 0x2100>>7); c.R != 255 || c.G != 255 {
+		t.Errorf("Pixel is %v", c)
+	}
+
+	if c := img.RGBAAt(0, 0); c.R != 0 || c.G != 0 {
+		t.Errorf("Pixel is %v", c)
+	}
+}
+
+func TestAccessMapRender(t *testing.T) {
+	ppu := NewRP2C02(nil)
+	ppu.Accesses = NewAccessMap()
+	ppu.Registers.Mask = uint8(ShowBackground)
+	ppu.quota = 0xffff
+
+	for ppu.cycle = 1; ppu.cycle <= 8; ppu.cycle++ {
+		ppu.Execute()
+	}
+
+	for _, address := range []uint16{0x2000, 0x23c0, 0x0000, 0x0008} {
+		if ppu.Accesses.Reads[RenderAccess][address] != 1 {
+			t.Errorf("Read of %04X is not counted", address)
+		}
+	}
+}
+
+func TestAccessMapPalette(t *testing.T) {
+	counts := [2][0x20]uint32{}
+
+	for i, renderer := range []Renderer{DotRenderer, ScanlineRenderer} {
+		ppu := newScene(renderer)
+		ppu.Accesses = NewAccessMap()
+
+		renderFrame(ppu, nil)
+
+		copy(counts[i][:], ppu.Accesses.Reads[RenderAccess][0x3f00:0x3f20])
+	}
+
+	total := uint32(0)
+
+	for _, count := range counts[0] {
+		total += count
+	}
+
+	if total != 256*240 {
+		t.Errorf("%d palette reads are counted not %d", total, 256*240)
+	}
+
+	if counts[0] != counts[1] {
+		t.Errorf("Palette reads are %v with the dot renderer but %v with the scanline renderer", counts[0], counts[1])
+	}
+}
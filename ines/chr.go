@@ -0,0 +1,76 @@
+package ines
+
+// CHR is CHR ROM or RAM mapped into the PPU's pattern tables in 4KB
+// banks.  Banks are numbered in 4KB units from the start of Data and
+// wrap around its size, so negative banks count back from its end.
+type CHR struct {
+	Data  []uint8
+	RAM   bool
+	Banks [2]int
+}
+
+func NewCHR(data []uint8, ram bool) *CHR {
+	return &CHR{
+		Data:  data,
+		RAM:   ram,
+		Banks: [2]int{0, 1},
+	}
+}
+
+// NumBanks returns the number of 4KB banks in Data.
+func (chr *CHR) NumBanks() int {
+	return (len(chr.Data) + 0x0fff) / 0x1000
+}
+
+// Select8K maps the 8KB bank at 0x0000 - 0x1fff.
+func (chr *CHR) Select8K(bank int) {
+	chr.Banks[0] = bank * 2
+	chr.Banks[1] = bank*2 + 1
+}
+
+// Select4K maps the 4KB bank at 0x0000 - 0x0fff when half is 0, or at
+// 0x1000 - 0x1fff when half is 1.
+func (chr *CHR) Select4K(half int, bank int) {
+	chr.Banks[half&0x01] = bank
+}
+
+func (chr *CHR) index(address uint16) (index int) {
+	if index = (chr.Banks[address>>12&0x01]*0x1000 + int(address&0x0fff)) % len(chr.Data); index < 0 {
+		index += len(chr.Data)
+	}
+
+	return
+}
+
+func (chr *CHR) Reset() {
+	if chr.RAM {
+		for i := range chr.Data {
+			chr.Data[i] = 0x00
+		}
+	}
+}
+
+func (chr *CHR) Fetch(address uint16) (value uint8) {
+	if len(chr.Data) == 0 {
+		return
+	}
+
+	value = chr.Data[chr.index(address)]
+
+	return
+}
+
+func (chr *CHR) Store(address uint16, value uint8) (oldValue uint8) {
+	if len(chr.Data) == 0 {
+		return
+	}
+
+	index := chr.index(address)
+	oldValue = chr.Data[index]
+
+	if chr.RAM {
+		chr.Data[index] = value
+	}
+
+	return
+}
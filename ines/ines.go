@@ -0,0 +1,387 @@
+// Package ines loads iNES and NES 2.0 ROM images and configures an
+// RP2C02 for them.
+package ines
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+type ConsoleType uint8
+
+const (
+	NES ConsoleType = iota
+	VsSystem
+	Playchoice10
+	ExtendedConsole
+)
+
+func (c ConsoleType) String() string {
+	switch c {
+	case NES:
+		return "NES"
+	case VsSystem:
+		return "VsSystem"
+	case Playchoice10:
+		return "Playchoice10"
+	case ExtendedConsole:
+		return "Extended"
+	}
+
+	return "Unknown"
+}
+
+type Timing uint8
+
+const (
+	NTSC Timing = iota
+	PAL
+	MultiRegion
+	Dendy
+)
+
+func (t Timing) String() string {
+	switch t {
+	case NTSC:
+		return "NTSC"
+	case PAL:
+		return "PAL"
+	case MultiRegion:
+		return "MultiRegion"
+	case Dendy:
+		return "Dendy"
+	}
+
+	return "Unknown"
+}
+
+// Region returns the PPU timing to use for t.  Multi-region ROMs run
+// as NTSC.
+func (t Timing) Region() rp2cgo2.Region {
+	switch t {
+	case PAL:
+		return rp2cgo2.PAL
+	case Dendy:
+		return rp2cgo2.Dendy
+	}
+
+	return rp2cgo2.NTSC
+}
+
+// VsPPU is the PPU fitted to a Vs. System board, from NES 2.0 byte 13.
+type VsPPU uint8
+
+const (
+	RP2C03B VsPPU = iota
+	RP2C03G
+	RP2C04_0001
+	RP2C04_0002
+	RP2C04_0003
+	RP2C04_0004
+	RC2C03B
+	RC2C03C
+	RC2C05_01
+	RC2C05_02
+	RC2C05_03
+	RC2C05_04
+	RC2C05_05
+)
+
+func (v VsPPU) String() string {
+	switch v {
+	case RP2C03B:
+		return "RP2C03B"
+	case RP2C03G:
+		return "RP2C03G"
+	case RP2C04_0001:
+		return "RP2C04-0001"
+	case RP2C04_0002:
+		return "RP2C04-0002"
+	case RP2C04_0003:
+		return "RP2C04-0003"
+	case RP2C04_0004:
+		return "RP2C04-0004"
+	case RC2C03B:
+		return "RC2C03B"
+	case RC2C03C:
+		return "RC2C03C"
+	case RC2C05_01:
+		return "RC2C05-01"
+	case RC2C05_02:
+		return "RC2C05-02"
+	case RC2C05_03:
+		return "RC2C05-03"
+	case RC2C05_04:
+		return "RC2C05-04"
+	case RC2C05_05:
+		return "RC2C05-05"
+	}
+
+	return "Unknown"
+}
+
+// Palette returns the palette output by v, or nil for the RP2C04
+// variants whose scrambled palettes are not included, which are left
+// with the default palette.
+func (v VsPPU) Palette() *rp2cgo2.Palette {
+	switch v {
+	case RP2C04_0001, RP2C04_0002, RP2C04_0003, RP2C04_0004:
+		return nil
+	case RP2C03B, RP2C03G, RC2C03B, RC2C03C, RC2C05_01, RC2C05_02, RC2C05_03, RC2C05_04, RC2C05_05:
+		return &rp2cgo2.RGBPalette
+	}
+
+	return nil
+}
+
+// SwapsRegisters returns whether v is an RC2C05, which has $2000 and
+// $2001 exchanged.
+func (v VsPPU) SwapsRegisters() bool {
+	return v >= RC2C05_01 && v <= RC2C05_05
+}
+
+var (
+	ErrMagic     = errors.New("ines: missing NES<EOF> magic number")
+	ErrTruncated = errors.New("ines: file is shorter than its header says")
+	ErrSize      = errors.New("ines: ROM size is out of range")
+)
+
+// Header is a decoded iNES or NES 2.0 header.  Sizes are in bytes.
+type Header struct {
+	NES20       bool
+	Mapper      uint16
+	Submapper   uint8
+	PRGROMSize  int
+	CHRROMSize  int
+	PRGRAMSize  int
+	CHRRAMSize  int
+	Mirroring   rp2cgo2.Mirroring
+	Battery     bool
+	Trainer     bool
+	ConsoleType ConsoleType
+	Timing      Timing
+	VsPPU       VsPPU
+}
+
+func romSize(lsb, msb uint8, unit int) (size int, err error) {
+	if msb == 0x0f {
+		// exponent-multiplier notation: 2^E * (MM*2+1)
+		if lsb>>2 >= 31 {
+			return 0, ErrSize
+		}
+
+		return (1 << (lsb >> 2)) * (int(lsb&0x03)*2 + 1), nil
+	}
+
+	return (int(msb)<<8 | int(lsb)) * unit, nil
+}
+
+func ramSize(shift uint8) int {
+	if shift == 0 {
+		return 0
+	}
+
+	return 64 << shift
+}
+
+func ParseHeader(header [16]uint8) (h Header, err error) {
+	if !bytes.Equal(header[0:4], []uint8("NES\x1a")) {
+		err = ErrMagic
+		return
+	}
+
+	h.NES20 = header[7]&0x0c == 0x08
+	h.Battery = header[6]&0x02 != 0
+	h.Trainer = header[6]&0x04 != 0
+
+	switch {
+	case header[6]&0x08 != 0:
+		h.Mirroring = rp2cgo2.FourScreen
+	case header[6]&0x01 != 0:
+		h.Mirroring = rp2cgo2.Vertical
+	default:
+		h.Mirroring = rp2cgo2.Horizontal
+	}
+
+	h.Mapper = uint16(header[6] >> 4)
+
+	switch {
+	case h.NES20:
+		h.Mapper |= uint16(header[7]&0xf0) | uint16(header[8]&0x0f)<<8
+		h.Submapper = header[8] >> 4
+		h.ConsoleType = ConsoleType(header[7] & 0x03)
+
+		if h.PRGROMSize, err = romSize(header[4], header[9]&0x0f, 0x4000); err != nil {
+			return
+		}
+
+		if h.CHRROMSize, err = romSize(header[5], header[9]>>4, 0x2000); err != nil {
+			return
+		}
+
+		h.PRGRAMSize = ramSize(header[10]&0x0f) + ramSize(header[10]>>4)
+		h.CHRRAMSize = ramSize(header[11]&0x0f) + ramSize(header[11]>>4)
+		h.Timing = Timing(header[12] & 0x03)
+
+		if h.ConsoleType == VsSystem {
+			h.VsPPU = VsPPU(header[13] & 0x0f)
+		}
+	default:
+		// archaic iNES: bytes 7-15 may hold garbage such as "DiskDude!"
+		if header[7]&0x0c != 0 || !bytes.Equal(header[12:16], []uint8{0, 0, 0, 0}) {
+			header[7] = 0
+			header[8] = 0
+			header[9] = 0
+		}
+
+		h.Mapper |= uint16(header[7] & 0xf0)
+		h.ConsoleType = ConsoleType(header[7] & 0x03)
+		h.PRGROMSize = int(header[4]) * 0x4000
+		h.CHRROMSize = int(header[5]) * 0x2000
+		h.PRGRAMSize = int(header[8]) * 0x2000
+
+		if h.PRGRAMSize == 0 {
+			h.PRGRAMSize = 0x2000
+		}
+
+		if h.CHRROMSize == 0 {
+			h.CHRRAMSize = 0x2000
+		}
+
+		if header[9]&0x01 != 0 {
+			h.Timing = PAL
+		}
+	}
+
+	if h.ConsoleType > Playchoice10 {
+		h.ConsoleType = ExtendedConsole
+	}
+
+	return
+}
+
+// ROM is a loaded ROM image.  CHR holds CHR ROM, or CHR RAM when the
+// ROM has none, and is nil if the cartridge has neither.
+type ROM struct {
+	Header
+	TrainerData []uint8
+	PRG         []uint8
+	CHR         *CHR
+}
+
+func Read(r io.Reader) (rom *ROM, err error) {
+	header := [16]uint8{}
+
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+
+	rom = &ROM{}
+
+	if rom.Header, err = ParseHeader(header); err != nil {
+		return nil, err
+	}
+
+	// sizes come from the header so are not trusted: the data is read
+	// as it arrives rather than allocated up front, and a size larger
+	// than the rest of the file is reported as truncated
+	read := func(size int) (data []uint8, err error) {
+		if size < 0 {
+			return nil, ErrSize
+		}
+
+		if data, err = io.ReadAll(io.LimitReader(r, int64(size))); err == nil && len(data) < size {
+			err = ErrTruncated
+		}
+
+		return
+	}
+
+	if rom.Trainer {
+		if rom.TrainerData, err = read(512); err != nil {
+			return nil, err
+		}
+	}
+
+	if rom.PRG, err = read(rom.PRGROMSize); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case rom.CHRROMSize != 0:
+		data, err := read(rom.CHRROMSize)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rom.CHR = NewCHR(data, false)
+	case rom.CHRRAMSize != 0:
+		rom.CHR = NewCHR(make([]uint8, rom.CHRRAMSize), true)
+	}
+
+	return
+}
+
+func Load(filename string) (rom *ROM, err error) {
+	f, err := os.Open(filename)
+
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	if rom, err = Read(f); err != nil {
+		err = fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return
+}
+
+// Config fills in the region, mirroring and pattern memory of config
+// for rom, and for Vs. System ROMs the palette and register layout of
+// its PPU.  The RC2C05's ID in the low bits of $2002 is not emulated.
+func (rom *ROM) Config(config rp2cgo2.Config) rp2cgo2.Config {
+	config.Region = rom.Timing.Region()
+	config.Mirroring = rom.Mirroring
+
+	if rom.CHR != nil {
+		config.Patterns = rom.CHR
+	}
+
+	if rom.ConsoleType == VsSystem {
+		if palette := rom.VsPPU.Palette(); palette != nil {
+			config.Palette = palette
+		}
+
+		config.SwapRegisters = rom.VsPPU.SwapsRegisters()
+	}
+
+	return config
+}
+
+// Configure applies rom's configuration, as set by Config, to an
+// existing PPU.  A region change takes effect at the start of the next
+// frame.
+func (rom *ROM) Configure(ppu *rp2cgo2.RP2C02) {
+	ppu.Region = rom.Timing.Region()
+	ppu.Memory.Mirroring = rom.Mirroring
+
+	if rom.CHR != nil {
+		ppu.Memory.Patterns = rom.CHR
+	}
+
+	if rom.ConsoleType == VsSystem {
+		if palette := rom.VsPPU.Palette(); palette != nil {
+			ppu.Palette = palette
+		}
+
+		ppu.SwapRegisters = rom.VsPPU.SwapsRegisters()
+	}
+}
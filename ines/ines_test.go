@@ -0,0 +1,214 @@
+package ines
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+func image(header []uint8, size int) []uint8 {
+	data := make([]uint8, 16+size)
+	copy(data, "NES\x1a")
+	copy(data[4:], header)
+
+	for i := 16; i < len(data); i++ {
+		data[i] = uint8(i)
+	}
+
+	return data
+}
+
+func TestReadINES(t *testing.T) {
+	rom, err := Read(bytes.NewReader(image([]uint8{2, 1, 0x41, 0x00, 0, 0x01}, 0x8000+0x2000)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.NES20 || rom.Mapper != 4 || rom.Mirroring != rp2cgo2.Vertical || rom.Timing != PAL {
+		t.Errorf("Header is %+v", rom.Header)
+	}
+
+	if len(rom.PRG) != 0x8000 || rom.CHR == nil || len(rom.CHR.Data) != 0x2000 || rom.CHR.RAM {
+		t.Fatalf("PRG is %d bytes and CHR is %v", len(rom.PRG), rom.CHR)
+	}
+
+	if rom.CHR.Fetch(0x0001) != 0x11 {
+		t.Errorf("CHR is %02X not 0x11", rom.CHR.Fetch(0x0001))
+	}
+
+	rom.CHR.Store(0x0000, 0xff)
+
+	if rom.CHR.Fetch(0x0000) == 0xff {
+		t.Error("CHR ROM was written")
+	}
+}
+
+func TestReadINESCHRRAM(t *testing.T) {
+	rom, err := Read(bytes.NewReader(image([]uint8{1, 0, 0x08}, 0x4000)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.Mirroring != rp2cgo2.FourScreen {
+		t.Errorf("Mirroring is %v not FourScreen", rom.Mirroring)
+	}
+
+	if rom.CHR == nil || !rom.CHR.RAM || len(rom.CHR.Data) != 0x2000 {
+		t.Fatalf("CHR is %v not 8KB of RAM", rom.CHR)
+	}
+
+	rom.CHR.Store(0x1234, 0xaa)
+
+	if rom.CHR.Fetch(0x1234) != 0xaa {
+		t.Errorf("CHR is %02X not 0xaa", rom.CHR.Fetch(0x1234))
+	}
+}
+
+func TestReadArchaicINES(t *testing.T) {
+	header := []uint8{1, 1, 0x10, 'D', 'i', 's', 'k', 'D', 'u', 'd', 'e', '!'}
+	rom, err := Read(bytes.NewReader(image(header, 0x4000+0x2000)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.Mapper != 1 || rom.Timing != NTSC {
+		t.Errorf("Mapper is %d and Timing %v not 1 and NTSC", rom.Mapper, rom.Timing)
+	}
+}
+
+func TestReadNES20(t *testing.T) {
+	// mapper 0x123, submapper 5, Vs. System with an RC2C05-02, CHR RAM
+	// and NVRAM of 8KB each, Dendy timing
+	header := []uint8{2, 0, 0x30, 0x29, 0x51, 0x00, 0x00, 0x77, 0x03, 0x19}
+	rom, err := Read(bytes.NewReader(image(header, 0x8000)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !rom.NES20 || rom.Mapper != 0x123 || rom.Submapper != 5 {
+		t.Errorf("Mapper is %d.%d not 291.5", rom.Mapper, rom.Submapper)
+	}
+
+	if rom.ConsoleType != VsSystem || rom.VsPPU != RC2C05_02 || rom.Timing != Dendy {
+		t.Errorf("Console is %v %v %v not VsSystem RC2C05-02 Dendy", rom.ConsoleType, rom.VsPPU, rom.Timing)
+	}
+
+	if rom.CHRRAMSize != 0x4000 || rom.CHR == nil || len(rom.CHR.Data) != 0x4000 || !rom.CHR.RAM {
+		t.Errorf("CHR RAM is %d bytes not 16KB", rom.CHRRAMSize)
+	}
+
+	ppu := rp2cgo2.NewRP2C02WithConfig(rom.Config(rp2cgo2.Config{}))
+
+	if ppu.Region != rp2cgo2.Dendy || ppu.Memory.Mirroring != rp2cgo2.Horizontal || ppu.Memory.Patterns != rom.CHR {
+		t.Errorf("PPU is configured for %v %v", ppu.Region, ppu.Memory.Mirroring)
+	}
+
+	if ppu.Palette != &rp2cgo2.RGBPalette || !ppu.SwapRegisters {
+		t.Error("PPU is not configured as an RC2C05")
+	}
+
+	ppu.Store(0x2000, 0x1e)
+
+	if ppu.Registers.Mask != 0x1e || ppu.Registers.Controller != 0x00 {
+		t.Errorf("Write to $2000 set Controller %02X Mask %02X", ppu.Registers.Controller, ppu.Registers.Mask)
+	}
+
+	other := rp2cgo2.NewRP2C02(nil)
+	rom.VsPPU = RP2C04_0001
+	rom.Configure(other)
+
+	if other.Palette != &rp2cgo2.DefaultPalette || other.SwapRegisters {
+		t.Error("RP2C04-0001 is not configured with the default palette")
+	}
+}
+
+func TestRomSize(t *testing.T) {
+	for _, test := range []struct {
+		lsb, msb uint8
+		unit     int
+		size     int
+	}{
+		{0x02, 0x00, 0x4000, 0x8000},
+		{0x00, 0x01, 0x4000, 0x400000},
+		{0x3d, 0x0f, 0x4000, 0x8000 * 3},
+	} {
+		if size, err := romSize(test.lsb, test.msb, test.unit); err != nil || size != test.size {
+			t.Errorf("Size of %02X %02X is %d not %d (%v)", test.lsb, test.msb, size, test.size, err)
+		}
+	}
+
+	for _, lsb := range []uint8{0x7c, 0xa0, 0xfc} {
+		if _, err := romSize(lsb, 0x0f, 0x4000); err != ErrSize {
+			t.Errorf("Size of %02X 0F error is %v not %v", lsb, err, ErrSize)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	data := image([]uint8{2, 1}, 0x8000)
+
+	if _, err := Read(bytes.NewReader(data)); err != ErrTruncated {
+		t.Errorf("Error is %v not %v", err, ErrTruncated)
+	}
+
+	// NES 2.0 exponent-multiplier sizes
+	for _, test := range []struct {
+		prg uint8
+		err error
+	}{
+		{0xfc, ErrSize},
+		{0xa0, ErrSize},
+		{0x70, ErrTruncated},
+	} {
+		data := image([]uint8{test.prg, 0, 0, 0x08, 0, 0x0f}, 0x100)
+
+		if _, err := Read(bytes.NewReader(data)); err != test.err {
+			t.Errorf("Error for PRG size %02X is %v not %v", test.prg, err, test.err)
+		}
+	}
+
+	data[3] = 0x00
+
+	if _, err := Read(bytes.NewReader(data)); err != ErrMagic {
+		t.Errorf("Error is %v not %v", err, ErrMagic)
+	}
+}
+
+func TestCHRBanks(t *testing.T) {
+	data := make([]uint8, 0x8000)
+
+	for bank := 0; bank < 8; bank++ {
+		data[bank*0x1000] = uint8(bank)
+	}
+
+	chr := NewCHR(data, false)
+
+	if chr.NumBanks() != 8 {
+		t.Errorf("NumBanks is %d not 8", chr.NumBanks())
+	}
+
+	chr.Select8K(2)
+
+	if chr.Fetch(0x0000) != 4 || chr.Fetch(0x1000) != 5 {
+		t.Errorf("Banks are %d and %d not 4 and 5", chr.Fetch(0x0000), chr.Fetch(0x1000))
+	}
+
+	chr.Select4K(1, 7)
+
+	if chr.Fetch(0x1000) != 7 {
+		t.Errorf("Bank is %d not 7", chr.Fetch(0x1000))
+	}
+
+	// negative banks wrap back from the end
+	chr.Select4K(0, -1)
+	chr.Select4K(1, -10)
+
+	if chr.Fetch(0x0000) != 7 || chr.Fetch(0x1000) != 6 {
+		t.Errorf("Banks -1 and -10 are %d and %d not 7 and 6", chr.Fetch(0x0000), chr.Fetch(0x1000))
+	}
+}
@@ -0,0 +1,180 @@
+package rp2cgo2
+
+import (
+	"math/rand"
+
+	"github.com/nwidger/m65go2"
+)
+
+// RAMPattern is the contents of the PPU's RAM (CHR RAM, nametables,
+// palette and OAM) at power on, which varies between consoles.
+type RAMPattern uint8
+
+const (
+	ZeroRAM RAMPattern = iota
+	OnesRAM
+	RandomRAM
+)
+
+func (p RAMPattern) String() string {
+	switch p {
+	case ZeroRAM:
+		return "Zero"
+	case OnesRAM:
+		return "Ones"
+	case RandomRAM:
+		return "Random"
+	}
+
+	return "Unknown"
+}
+
+func (p RAMPattern) values(seed int64) func() uint8 {
+	switch p {
+	case OnesRAM:
+		return func() uint8 { return 0xff }
+	case RandomRAM:
+		r := rand.New(rand.NewSource(seed))
+		return func() uint8 { return uint8(r.Intn(256)) }
+	}
+
+	return func() uint8 { return 0x00 }
+}
+
+// Memory is the PPU's 16KB address space, decoded arithmetically rather
+// than through a mirror table:
+//
+//	0x0000 - 0x1fff  pattern tables, Patterns or internal CHR RAM
+//	0x2000 - 0x2fff  nametables, mirrored according to Mirroring
+//	0x3000 - 0x3eff  mirror of 0x2000 - 0x2eff
+//	0x3f00 - 0x3f1f  palette, 0x3f10/0x3f14/0x3f18/0x3f1c mirror 0x3f00/0x3f04/0x3f08/0x3f0c
+//	0x3f20 - 0x3fff  mirrors of 0x3f00 - 0x3f1f
+//
+// Addresses above 0x3fff are mirrors of 0x0000 - 0x3fff.
+type Memory struct {
+	Patterns   m65go2.Memory
+	Mirroring  Mirroring
+	chr        [0x2000]uint8
+	nametables [0x1000]uint8
+	palette    [0x20]uint8
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		Mirroring: FourScreen,
+	}
+}
+
+func (mem *Memory) Reset() {
+	mem.chr = [0x2000]uint8{}
+	mem.nametables = [0x1000]uint8{}
+	mem.palette = [0x20]uint8{}
+}
+
+func (mem *Memory) fill(value func() uint8) {
+	if mem.Patterns == nil {
+		for i := range mem.chr {
+			mem.chr[i] = value()
+		}
+	}
+
+	for i := range mem.nametables {
+		mem.nametables[i] = value()
+	}
+
+	for i := range mem.palette {
+		mem.palette[i] = value() & 0x3f
+	}
+}
+
+func (mem *Memory) nametable(address uint16) uint16 {
+	//    NN ii iiii iiii
+	// Horizontal: 0x2000 = 0x2400, 0x2800 = 0x2c00
+	// Vertical:   0x2000 = 0x2800, 0x2400 = 0x2c00
+	switch mem.Mirroring {
+	case Horizontal:
+		address = (address&0x0800)>>1 | address&0x03ff
+	case Vertical:
+		address &= 0x07ff
+	default:
+		address &= 0x0fff
+	}
+
+	return address
+}
+
+func (mem *Memory) paletteIndex(address uint16) uint16 {
+	address &= 0x001f
+
+	if address&0x0013 == 0x0010 {
+		address &^= 0x0010
+	}
+
+	return address
+}
+
+func (mem *Memory) Fetch(address uint16) (value uint8) {
+	address &= 0x3fff
+
+	switch {
+	case address < 0x2000:
+		if mem.Patterns != nil {
+			value = mem.Patterns.Fetch(address)
+		} else {
+			value = mem.chr[address]
+		}
+	case address < 0x3f00:
+		value = mem.nametables[mem.nametable(address)]
+	default:
+		value = mem.palette[mem.paletteIndex(address)]
+	}
+
+	return
+}
+
+func (mem *Memory) Store(address uint16, value uint8) (oldValue uint8) {
+	address &= 0x3fff
+
+	switch {
+	case address < 0x2000:
+		if mem.Patterns != nil {
+			oldValue = mem.Patterns.Store(address, value)
+		} else {
+			oldValue = mem.chr[address]
+			mem.chr[address] = value
+		}
+	case address < 0x3f00:
+		index := mem.nametable(address)
+		oldValue = mem.nametables[index]
+		mem.nametables[index] = value
+	default:
+		index := mem.paletteIndex(address)
+		oldValue = mem.palette[index]
+		mem.palette[index] = value
+	}
+
+	return
+}
+
+// CopyTo copies the pattern tables, nametables and palette of mem into
+// dst's own RAM.  dst is left with four nametables holding the four
+// that mem addresses, so mirrored nametables appear as the PPU sees them.
+func (mem *Memory) CopyTo(dst *Memory) {
+	dst.Patterns = nil
+	dst.Mirroring = FourScreen
+
+	if mem.Patterns != nil {
+		for address := range dst.chr {
+			dst.chr[address] = mem.Patterns.Fetch(uint16(address))
+		}
+	} else {
+		dst.chr = mem.chr
+	}
+
+	for i := uint16(0); i < 4; i++ {
+		index := mem.nametable(0x2000 | i<<10)
+		copy(dst.nametables[i<<10:(i+1)<<10], mem.nametables[index:index+0x0400])
+	}
+
+	dst.palette = mem.palette
+}
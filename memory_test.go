@@ -0,0 +1,129 @@
+package rp2cgo2
+
+import (
+	"testing"
+
+	"github.com/nwidger/m65go2"
+	"github.com/nwidger/rp2ago3"
+)
+
+func TestNametableMirroring(t *testing.T) {
+	for _, test := range []struct {
+		mirroring Mirroring
+		mirrors   [4]uint16
+	}{
+		{Horizontal, [4]uint16{0x2000, 0x2000, 0x2800, 0x2800}},
+		{Vertical, [4]uint16{0x2000, 0x2400, 0x2000, 0x2400}},
+		{FourScreen, [4]uint16{0x2000, 0x2400, 0x2800, 0x2c00}},
+	} {
+		mem := NewMemory()
+		mem.Mirroring = test.mirroring
+
+		for i, mirror := range test.mirrors {
+			address := 0x2000 | uint16(i)<<10 | 0x0123
+
+			mem.Store(address, uint8(i+1))
+
+			if mem.Fetch(mirror|0x0123) != uint8(i+1) {
+				t.Errorf("%v: %04X is not mirrored at %04X", test.mirroring, address, mirror|0x0123)
+			}
+
+			if mem.Fetch(address+0x1000) != uint8(i+1) && address+0x1000 < 0x3f00 {
+				t.Errorf("%v: %04X is not mirrored at %04X", test.mirroring, address, address+0x1000)
+			}
+		}
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	mem := NewMemory()
+
+	mem.Store(0x1234, 0xff)
+
+	if mem.Fetch(0x1234) != 0xff {
+		t.Error("Memory is not 0xff")
+	}
+
+	mem.Patterns = m65go2.NewBasicMemory(0x2000)
+
+	if mem.Fetch(0x1234) != 0x00 {
+		t.Error("Memory is not 0x00")
+	}
+
+	mem.Store(0x0010, 0xaa)
+
+	if mem.Patterns.Fetch(0x0010) != 0xaa {
+		t.Error("Memory is not 0xaa")
+	}
+}
+
+func TestCopyTo(t *testing.T) {
+	mem := NewMemory()
+	mem.Mirroring = Vertical
+	mem.Patterns = m65go2.NewBasicMemory(0x2000)
+
+	mem.Store(0x0010, 0xaa)
+	mem.Store(0x2123, 0x01)
+	mem.Store(0x2523, 0x02)
+	mem.Store(0x3f11, 0x16)
+
+	dst := &Memory{}
+	mem.CopyTo(dst)
+
+	if dst.Patterns != nil || dst.Mirroring != FourScreen {
+		t.Errorf("Copy has patterns %v and %v mirroring", dst.Patterns, dst.Mirroring)
+	}
+
+	for address, value := range map[uint16]uint8{
+		0x0010: 0xaa,
+		0x2123: 0x01, 0x2523: 0x02, 0x2923: 0x01, 0x2d23: 0x02,
+		0x3f11: 0x16,
+	} {
+		if dst.Fetch(address) != value {
+			t.Errorf("Copy at %04X is %02X not %02X", address, dst.Fetch(address), value)
+		}
+	}
+
+	mem.Store(0x2123, 0x03)
+
+	if dst.Fetch(0x2123) != 0x01 {
+		t.Error("Copy shares its nametables")
+	}
+}
+
+func newMappedMemory() *rp2ago3.MappedMemory {
+	mem := rp2ago3.NewMappedMemory(m65go2.NewBasicMemory(m65go2.DEFAULT_MEMORY_SIZE))
+	mirrors := make(map[uint16]uint16)
+
+	for i := uint16(0x3000); i <= 0x3eff; i++ {
+		mirrors[i] = i - 0x1000
+	}
+
+	for _, i := range []uint16{0x3f10, 0x3f14, 0x3f18, 0x3f1c} {
+		mirrors[i] = i - 0x0010
+	}
+
+	for i := uint16(0x3f20); i <= 0x3fff; i++ {
+		mirrors[i] = i - 0x0020
+	}
+
+	mem.AddMirrors(mirrors)
+
+	return mem
+}
+
+func benchmarkMemory(b *testing.B, mem m65go2.Memory) {
+	for i := 0; i < b.N; i++ {
+		for address := uint16(0x0000); address <= 0x3fff; address++ {
+			mem.Store(address, mem.Fetch(address)+1)
+		}
+	}
+}
+
+func BenchmarkMappedMemory(b *testing.B) {
+	benchmarkMemory(b, newMappedMemory())
+}
+
+func BenchmarkMemory(b *testing.B) {
+	benchmarkMemory(b, NewMemory())
+}
@@ -0,0 +1,200 @@
+// Package nametable imports and exports the PPU's nametables and their
+// attribute tables as raw .nam/.atr files, Tiled TMX maps and images.
+package nametable
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+const (
+	Width  = 32
+	Height = 30
+)
+
+var ErrSize = errors.New("nametable: file is the wrong size")
+
+// Nametable is one 1KB nametable: 32x30 tile numbers followed by a
+// 64 byte attribute table giving a palette to each 16x16 pixel area.
+type Nametable struct {
+	Tiles      [Width * Height]uint8
+	Attributes [64]uint8
+}
+
+func address(index int) uint16 {
+	return 0x2000 | uint16(index&0x03)<<10
+}
+
+// Read copies nametable index (0-3) out of mem.
+func Read(mem *rp2cgo2.Memory, index int) (nt *Nametable) {
+	nt = &Nametable{}
+	base := address(index)
+
+	for i := range nt.Tiles {
+		nt.Tiles[i] = mem.Fetch(base + uint16(i))
+	}
+
+	for i := range nt.Attributes {
+		nt.Attributes[i] = mem.Fetch(base + 0x03c0 + uint16(i))
+	}
+
+	return
+}
+
+// Write copies nt into nametable index (0-3) of mem.
+func (nt *Nametable) Write(mem *rp2cgo2.Memory, index int) {
+	base := address(index)
+
+	for i, tile := range nt.Tiles {
+		mem.Store(base+uint16(i), tile)
+	}
+
+	for i, attribute := range nt.Attributes {
+		mem.Store(base+0x03c0+uint16(i), attribute)
+	}
+}
+
+// attribute returns the attribute byte index and bit shift covering
+// tile x, y, as the PPU selects them when fetching attributes.
+func attribute(x, y int) (index int, shift uint) {
+	return (y>>2)<<3 | x>>2, uint((x & 0x02) | (y&0x02)<<1)
+}
+
+// Palette returns the background palette (0-3) used by tile x, y.
+func (nt *Nametable) Palette(x, y int) uint8 {
+	index, shift := attribute(x, y)
+	return (nt.Attributes[index] >> shift) & 0x03
+}
+
+// SetPalette sets the palette of the 16x16 area containing tile x, y.
+func (nt *Nametable) SetPalette(x, y int, palette uint8) {
+	index, shift := attribute(x, y)
+	nt.Attributes[index] = nt.Attributes[index]&^(0x03<<shift) | (palette&0x03)<<shift
+}
+
+// SetPalettes sets the attribute table from one palette per tile.
+// Palettes must be the same for all four tiles of each 16x16 area.
+func (nt *Nametable) SetPalettes(palettes *[Width * Height]uint8) error {
+	for y := 0; y < Height; y++ {
+		for x := 0; x < Width; x++ {
+			palette := palettes[y*Width+x]
+
+			if palette > 3 {
+				return fmt.Errorf("nametable: tile %d,%d has palette %d", x, y, palette)
+			}
+
+			if first := palettes[(y&^1)*Width+x&^1]; palette != first {
+				return fmt.Errorf("nametable: tile %d,%d has palette %d not %d like the rest of its 16x16 area", x, y, palette, first)
+			}
+
+			nt.SetPalette(x, y, palette)
+		}
+	}
+
+	return nil
+}
+
+// WriteNAM writes the 960 tile numbers.
+func (nt *Nametable) WriteNAM(w io.Writer) (err error) {
+	_, err = w.Write(nt.Tiles[:])
+	return
+}
+
+// ReadNAM reads 960 tile numbers, or a full 1024 byte nametable
+// including its attribute table.
+func (nt *Nametable) ReadNAM(r io.Reader) (err error) {
+	data, err := readAll(r, 1024)
+
+	if err != nil {
+		return
+	}
+
+	switch len(data) {
+	case 1024:
+		copy(nt.Attributes[:], data[960:])
+		fallthrough
+	case 960:
+		copy(nt.Tiles[:], data)
+	default:
+		err = ErrSize
+	}
+
+	return
+}
+
+// WriteATR writes the 64 byte attribute table.
+func (nt *Nametable) WriteATR(w io.Writer) (err error) {
+	_, err = w.Write(nt.Attributes[:])
+	return
+}
+
+func (nt *Nametable) ReadATR(r io.Reader) (err error) {
+	data, err := readAll(r, 64)
+
+	if err != nil {
+		return
+	}
+
+	if len(data) != 64 {
+		return ErrSize
+	}
+
+	copy(nt.Attributes[:], data)
+
+	return
+}
+
+func readAll(r io.Reader, max int) (data []uint8, err error) {
+	data = make([]uint8, max+1)
+	n, err := io.ReadFull(r, data)
+
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF:
+		err = nil
+	case nil:
+		err = ErrSize
+	}
+
+	return data[:n], err
+}
+
+// Image draws the four nametables of mem as a 512x480 image, arranged
+// as they are addressed by the PPU's scroll registers, using the
+// pattern table at base (0x0000 or 0x1000) and mem's background
+// palettes.
+func Image(mem *rp2cgo2.Memory, palette *rp2cgo2.Palette, base uint16) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, Width*8*2, Height*8*2))
+
+	for index := 0; index < 4; index++ {
+		nt := Read(mem, index)
+		left, top := (index&0x01)*Width*8, (index>>1)*Height*8
+
+		for y := 0; y < Height; y++ {
+			for x := 0; x < Width; x++ {
+				pattern := base | uint16(nt.Tiles[y*Width+x])<<4
+				colors := uint16(nt.Palette(x, y)) << 2
+
+				for row := 0; row < 8; row++ {
+					low := mem.Fetch(pattern + uint16(row))
+					high := mem.Fetch(pattern + uint16(row) + 8)
+
+					for col := 0; col < 8; col++ {
+						value := uint16((low>>uint(7-col))&0x01 | ((high>>uint(7-col))&0x01)<<1)
+
+						if value != 0 {
+							value |= colors
+						}
+
+						img.SetRGBA(left+x*8+col, top+y*8+row, palette.RGBA(mem.Fetch(0x3f00|value)))
+					}
+				}
+			}
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,174 @@
+package nametable
+
+import (
+	"bytes"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+func randomMemory() *rp2cgo2.Memory {
+	r := rand.New(rand.NewSource(1))
+	mem := rp2cgo2.NewMemory()
+
+	for address := uint16(0x0000); address < 0x3000; address++ {
+		mem.Store(address, uint8(r.Intn(256)))
+	}
+
+	return mem
+}
+
+func TestPalette(t *testing.T) {
+	nt := &Nametable{}
+
+	// top left, top right, bottom left, bottom right
+	nt.Attributes[9] = 0x1b
+
+	for _, test := range []struct {
+		x, y    int
+		palette uint8
+	}{
+		{4, 4, 3}, {5, 5, 3}, {6, 4, 2}, {4, 6, 1}, {7, 7, 0}, {3, 3, 0},
+	} {
+		if palette := nt.Palette(test.x, test.y); palette != test.palette {
+			t.Errorf("Tile %d,%d has palette %d not %d", test.x, test.y, palette, test.palette)
+		}
+	}
+
+	nt.SetPalette(7, 6, 2)
+
+	if nt.Attributes[9] != 0x9b {
+		t.Errorf("Attribute is %02X not 0x9b", nt.Attributes[9])
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	mem := randomMemory()
+	nt := Read(mem, 2)
+
+	if nt.Tiles[0] != mem.Fetch(0x2800) || nt.Attributes[63] != mem.Fetch(0x2bff) {
+		t.Error("Nametable 2 was not read from 0x2800")
+	}
+
+	other := rp2cgo2.NewMemory()
+	nt.Write(other, 1)
+
+	for address := uint16(0x0000); address < 0x0400; address++ {
+		if other.Fetch(0x2400+address) != mem.Fetch(0x2800+address) {
+			t.Fatalf("%04X is %02X not %02X", 0x2400+address, other.Fetch(0x2400+address), mem.Fetch(0x2800+address))
+		}
+	}
+}
+
+func TestNAMATR(t *testing.T) {
+	nt := Read(randomMemory(), 0)
+	nam, atr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	if err := nt.WriteNAM(nam); err != nil || nam.Len() != 960 {
+		t.Fatalf("Wrote %d bytes: %v", nam.Len(), err)
+	}
+
+	if err := nt.WriteATR(atr); err != nil || atr.Len() != 64 {
+		t.Fatalf("Wrote %d bytes: %v", atr.Len(), err)
+	}
+
+	full := append(append([]uint8{}, nam.Bytes()...), atr.Bytes()...)
+	read := &Nametable{}
+
+	if err := read.ReadNAM(nam); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := read.ReadATR(atr); err != nil {
+		t.Fatal(err)
+	}
+
+	if *read != *nt {
+		t.Error("Nametable read back does not match")
+	}
+
+	read = &Nametable{}
+
+	if err := read.ReadNAM(bytes.NewReader(full)); err != nil || *read != *nt {
+		t.Errorf("1024 byte nametable read back does not match: %v", err)
+	}
+
+	if err := read.ReadNAM(bytes.NewReader(full[:1000])); err != ErrSize {
+		t.Errorf("Error is %v not %v", err, ErrSize)
+	}
+
+	if err := read.ReadATR(bytes.NewReader(full)); err != ErrSize {
+		t.Errorf("Error is %v not %v", err, ErrSize)
+	}
+}
+
+func TestTMX(t *testing.T) {
+	mem := randomMemory()
+	nts := [4]*Nametable{}
+
+	for i := range nts {
+		nts[i] = Read(mem, i)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := WriteTMX(buf, &nts, "chr.png"); err != nil {
+		t.Fatal(err)
+	}
+
+	text := buf.String()
+	read, err := ReadTMX(buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range nts {
+		if read[i].Tiles != nts[i].Tiles {
+			t.Errorf("Nametable %d tiles do not match", i)
+		}
+
+		for y := 0; y < Height; y++ {
+			for x := 0; x < Width; x++ {
+				if read[i].Palette(x, y) != nts[i].Palette(x, y) {
+					t.Fatalf("Nametable %d tile %d,%d has palette %d not %d", i, x, y, read[i].Palette(x, y), nts[i].Palette(x, y))
+				}
+			}
+		}
+	}
+
+	// give the top left tile of nametable 0 a different palette from the
+	// rest of its 16x16 area
+	first := strings.Index(text, `encoding="csv">`) + len(`encoding="csv">`) + 1
+	end := first + strings.Index(text[first:], ",")
+	gid := 1 + (int(nts[0].Palette(0, 0))+1)%4*256
+
+	if _, err := ReadTMX(strings.NewReader(text[:first] + strconv.Itoa(gid) + text[end:])); err == nil || !strings.Contains(err.Error(), "16x16") {
+		t.Errorf("Mixed palettes in a 16x16 area gave error %v", err)
+	}
+
+	// flip the top left tile horizontally
+	flipped := strconv.Itoa(int(nts[0].Tiles[0]) + 1 + int(nts[0].Palette(0, 0))*256 | 0x80000000)
+
+	if _, err := ReadTMX(strings.NewReader(text[:first] + flipped + text[end:])); err == nil || !strings.Contains(err.Error(), "tile 0,0 is flipped") {
+		t.Errorf("Flipped tile gave error %v", err)
+	}
+}
+
+func TestImage(t *testing.T) {
+	mem := randomMemory()
+	img := Image(mem, &rp2cgo2.DefaultPalette, 0x0000)
+
+	if img.Bounds().Dx() != 512 || img.Bounds().Dy() != 480 {
+		t.Errorf("Image is %v not 512x480", img.Bounds())
+	}
+
+	tileset := Tileset(mem, &rp2cgo2.DefaultPalette, 0x1000)
+
+	if tileset.Bounds().Dx() != 128 || tileset.Bounds().Dy() != 512 {
+		t.Errorf("Tileset is %v not 128x512", tileset.Bounds())
+	}
+}
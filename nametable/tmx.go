@@ -0,0 +1,230 @@
+package nametable
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+// The TMX map is 64x60 tiles holding all four nametables arranged as in
+// Image.  It uses a single tileset of 1024 8x8 tiles: the 256 tiles of
+// a pattern table drawn once with each of the four background palettes,
+// so that a tile's global ID is 1 + palette*256 + tile number.
+
+type tmxMap struct {
+	XMLName      xml.Name     `xml:"map"`
+	Version      string       `xml:"version,attr"`
+	Orientation  string       `xml:"orientation,attr"`
+	RenderOrder  string       `xml:"renderorder,attr"`
+	Width        int          `xml:"width,attr"`
+	Height       int          `xml:"height,attr"`
+	TileWidth    int          `xml:"tilewidth,attr"`
+	TileHeight   int          `xml:"tileheight,attr"`
+	NextObjectID int          `xml:"nextobjectid,attr"`
+	Tilesets     []tmxTileset `xml:"tileset"`
+	Layers       []tmxLayer   `xml:"layer"`
+}
+
+type tmxTileset struct {
+	FirstGID   int       `xml:"firstgid,attr"`
+	Name       string    `xml:"name,attr"`
+	TileWidth  int       `xml:"tilewidth,attr"`
+	TileHeight int       `xml:"tileheight,attr"`
+	TileCount  int       `xml:"tilecount,attr"`
+	Columns    int       `xml:"columns,attr"`
+	Image      *tmxImage `xml:"image"`
+}
+
+type tmxImage struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+type tmxLayer struct {
+	ID     int     `xml:"id,attr"`
+	Name   string  `xml:"name,attr"`
+	Width  int     `xml:"width,attr"`
+	Height int     `xml:"height,attr"`
+	Data   tmxData `xml:"data"`
+}
+
+type tmxData struct {
+	Encoding string `xml:"encoding,attr"`
+	Text     string `xml:",innerxml"`
+}
+
+const (
+	tmxFlipFlags = 0xe0000000
+	tmxWidth     = Width * 2
+	tmxHeight    = Height * 2
+)
+
+// Tileset draws the tileset image referenced by TMX maps: the pattern
+// table at base drawn with each of mem's four background palettes, one
+// 128x128 block per palette stacked vertically.
+func Tileset(mem *rp2cgo2.Memory, palette *rp2cgo2.Palette, base uint16) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 128, 512))
+
+	for p := uint16(0); p < 4; p++ {
+		for tile := uint16(0); tile < 256; tile++ {
+			left, top := int(tile&0x0f)*8, int(p)*128+int(tile>>4)*8
+			pattern := base | tile<<4
+
+			for row := uint16(0); row < 8; row++ {
+				low := mem.Fetch(pattern + row)
+				high := mem.Fetch(pattern + row + 8)
+
+				for col := 0; col < 8; col++ {
+					value := uint16((low>>uint(7-col))&0x01 | ((high>>uint(7-col))&0x01)<<1)
+
+					if value != 0 {
+						value |= p << 2
+					}
+
+					img.SetRGBA(left+col, top+int(row), palette.RGBA(mem.Fetch(0x3f00|value)))
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// WriteTMX writes nts as a Tiled map using the tileset image at
+// tileset, as drawn by Tileset.
+func WriteTMX(w io.Writer, nts *[4]*Nametable, tileset string) (err error) {
+	data := make([]string, 0, tmxWidth*tmxHeight)
+
+	for y := 0; y < tmxHeight; y++ {
+		for x := 0; x < tmxWidth; x++ {
+			nt := nts[(y/Height)<<1|x/Width]
+			tx, ty := x%Width, y%Height
+			gid := 1 + int(nt.Palette(tx, ty))*256 + int(nt.Tiles[ty*Width+tx])
+			data = append(data, strconv.Itoa(gid))
+		}
+	}
+
+	m := tmxMap{
+		Version:      "1.0",
+		Orientation:  "orthogonal",
+		RenderOrder:  "right-down",
+		Width:        tmxWidth,
+		Height:       tmxHeight,
+		TileWidth:    8,
+		TileHeight:   8,
+		NextObjectID: 1,
+		Tilesets: []tmxTileset{{
+			FirstGID:   1,
+			Name:       "chr",
+			TileWidth:  8,
+			TileHeight: 8,
+			TileCount:  1024,
+			Columns:    16,
+			Image:      &tmxImage{Source: tileset, Width: 128, Height: 512},
+		}},
+		Layers: []tmxLayer{{
+			ID:     1,
+			Name:   "nametables",
+			Width:  tmxWidth,
+			Height: tmxHeight,
+			Data:   tmxData{Encoding: "csv", Text: "\n" + strings.Join(data, ",") + "\n"},
+		}},
+	}
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", " ")
+
+	if err = e.Encode(m); err != nil {
+		return
+	}
+
+	_, err = io.WriteString(w, "\n")
+
+	return
+}
+
+// ReadTMX reads a Tiled map written by WriteTMX.  The first layer must
+// be 64x60 CSV data using only the map's first tileset, and every
+// 16x16 area must use tiles drawn with a single palette.
+func ReadTMX(r io.Reader) (nts [4]*Nametable, err error) {
+	m := tmxMap{}
+
+	if err = xml.NewDecoder(r).Decode(&m); err != nil {
+		return
+	}
+
+	if len(m.Layers) == 0 || len(m.Tilesets) == 0 {
+		err = fmt.Errorf("nametable: map has no layers or tilesets")
+		return
+	}
+
+	layer := m.Layers[0]
+
+	if layer.Width != tmxWidth || layer.Height != tmxHeight {
+		err = fmt.Errorf("nametable: layer is %dx%d not %dx%d", layer.Width, layer.Height, tmxWidth, tmxHeight)
+		return
+	}
+
+	if layer.Data.Encoding != "csv" {
+		err = fmt.Errorf("nametable: layer encoding is %q not csv", layer.Data.Encoding)
+		return
+	}
+
+	fields := strings.Split(strings.TrimSpace(layer.Data.Text), ",")
+
+	if len(fields) != tmxWidth*tmxHeight {
+		err = fmt.Errorf("nametable: layer has %d tiles not %d", len(fields), tmxWidth*tmxHeight)
+		return
+	}
+
+	palettes := [4][Width * Height]uint8{}
+
+	for i := range nts {
+		nts[i] = &Nametable{}
+	}
+
+	for i, field := range fields {
+		x, y := i%tmxWidth, i/tmxWidth
+		gid, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+
+		if err != nil {
+			return nts, fmt.Errorf("nametable: tile %d,%d: %v", x, y, err)
+		}
+
+		// background tiles cannot be flipped or rotated, so importing a
+		// flipped tile would silently draw different art
+		if gid&tmxFlipFlags != 0 {
+			return nts, fmt.Errorf("nametable: tile %d,%d is flipped or rotated, which the NES cannot show", x, y)
+		}
+
+		index := int(gid) - m.Tilesets[0].FirstGID
+
+		if index < 0 || index >= 1024 {
+			return nts, fmt.Errorf("nametable: tile %d,%d has global ID %d outside the tileset", x, y, gid)
+		}
+
+		nt := (y/Height)<<1 | x/Width
+		offset := (y%Height)*Width + x%Width
+
+		nts[nt].Tiles[offset] = uint8(index)
+		palettes[nt][offset] = uint8(index >> 8)
+	}
+
+	for i, nt := range nts {
+		if err = nt.SetPalettes(&palettes[i]); err != nil {
+			return nts, fmt.Errorf("%v in nametable %d", err, i)
+		}
+	}
+
+	return
+}
@@ -8,6 +8,8 @@ type OAM struct {
 	latch      uint8
 	Buffer     *m65go2.BasicMemory
 	index      uint16
+	idle       bool
+	spriteZero bool
 	readCycle  func(oam *OAM, scanline uint16, cycle uint16, size uint16)
 	writeCycle func(oam *OAM, scanline uint16, cycle uint16, size uint16) (spriteOverflow bool)
 }
@@ -37,6 +39,7 @@ func (oam *OAM) SpriteEvaluation(scanline uint16, cycle uint16, size uint16) (sp
 			oam.address = 0
 			oam.latch = 0xff
 			oam.index = 0
+			oam.idle = false
 
 			oam.Buffer.EnableWrites()
 			oam.DisableReads()
@@ -45,6 +48,8 @@ func (oam *OAM) SpriteEvaluation(scanline uint16, cycle uint16, size uint16) (sp
 			oam.address = 0
 			oam.latch = 0xff
 			oam.index = 0
+			oam.idle = false
+			oam.spriteZero = false
 
 			oam.EnableReads()
 			oam.writeCycle = copyYPosition
@@ -65,6 +70,69 @@ func (oam *OAM) SpriteEvaluation(scanline uint16, cycle uint16, size uint16) (sp
 	return
 }
 
+// evaluate performs cycles 1 through 256 of sprite evaluation at once,
+// leaving Buffer, the sprite zero flag and the overflow it reports as
+// SpriteEvaluation would.  Rather than stepping the per-cycle state
+// machine it walks OAM directly: in range sprites are copied until
+// Buffer is full, then the remaining sprites are checked for overflow
+// with the hardware's diagonal address increment.  Evaluation of all
+// 64 sprites always finishes well within the 192 cycles available.
+func (oam *OAM) evaluate(scanline uint16, size uint16) (spriteOverflow bool) {
+	if scanline > 239 {
+		return
+	}
+
+	oam.SpriteEvaluation(scanline, 1, size)
+
+	for address := uint16(0); address < 32; address++ {
+		oam.Buffer.Store(address, 0xff)
+	}
+
+	oam.EnableReads()
+	oam.spriteZero = false
+
+	address, index := uint16(0), uint16(0)
+
+	for ; address < 0x0100 && index < 32; address += 4 {
+		y := oam.Fetch(address)
+
+		if scanline-uint16(y) >= size {
+			continue
+		}
+
+		if address == 0 {
+			oam.spriteZero = true
+		}
+
+		oam.Buffer.Store(index, y)
+		oam.Buffer.Store(index+1, oam.Fetch(address+1))
+		oam.Buffer.Store(index+2, oam.Fetch(address+2))
+		oam.Buffer.Store(index+3, oam.Fetch(address+3))
+		index += 4
+	}
+
+	if index == 32 && address < 0x0100 {
+		oam.Buffer.DisableWrites()
+
+		// the byte examined moves along one on each failed check
+		for m := uint16(0); address < 0x0100; address += 4 {
+			if scanline-uint16(oam.Fetch(address+m)) < size {
+				spriteOverflow = true
+				break
+			}
+
+			m = (m + 1) & 0x0003
+		}
+	}
+
+	oam.address = address & 0x00ff
+	oam.index = index
+	oam.idle = true
+	oam.writeCycle = failCopyYPosition
+
+	return
+}
+
 func fetchAddress(oam *OAM, scanline uint16, cycle uint16, size uint16) {
 	if oam.address < 0x0100 {
 		oam.latch = oam.Fetch(oam.address)
@@ -80,6 +148,10 @@ func clearBuffer(oam *OAM, scanline uint16, cycle uint16, size uint16) (spriteOv
 
 func copyYPosition(oam *OAM, scanline uint16, cycle uint16, size uint16) (spriteOverflow bool) {
 	if scanline-uint16(oam.latch) < size {
+		if oam.address == 0 {
+			oam.spriteZero = true
+		}
+
 		oam.Buffer.Store(oam.index+0, oam.latch)
 		oam.writeCycle = copyIndex
 		oam.address++
@@ -172,6 +244,7 @@ func evaluateXPosition(oam *OAM, scanline uint16, cycle uint16, size uint16) (sp
 
 func failCopyYPosition(oam *OAM, scanline uint16, cycle uint16, size uint16) (spriteOverflow bool) {
 	oam.address = (oam.address + 4) & 0x00ff
+	oam.idle = true
 
 	return
 }
@@ -1,6 +1,7 @@
 package rp2cgo2
 
 import (
+	"math/rand"
 	"testing"
 )
 
@@ -563,3 +564,45 @@ func TestOAMSprite(t *testing.T) {
 	}
 
 }
+
+func TestEvaluate(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	expected := NewOAM()
+	actual := NewOAM()
+
+	for i := uint16(0); i < 256; i++ {
+		value := uint8(r.Intn(256))
+
+		expected.Store(i, value)
+		actual.Store(i, value)
+	}
+
+	for _, size := range []uint16{8, 16} {
+		for scanline := uint16(0); scanline <= 239; scanline++ {
+			expectedOverflow := false
+
+			for cycle := uint16(1); cycle <= 256; cycle++ {
+				if expected.SpriteEvaluation(scanline, cycle, size) {
+					expectedOverflow = true
+				}
+			}
+
+			actualOverflow := actual.evaluate(scanline, size)
+
+			if actualOverflow != expectedOverflow {
+				t.Errorf("Scanline %d overflow is %v not %v", scanline, actualOverflow, expectedOverflow)
+			}
+
+			if actual.spriteZero != expected.spriteZero {
+				t.Errorf("Scanline %d sprite zero is %v not %v", scanline, actual.spriteZero, expected.spriteZero)
+			}
+
+			for i := uint16(0); i < 32; i++ {
+				if actual.Buffer.Fetch(i) != expected.Buffer.Fetch(i) {
+					t.Errorf("Scanline %d memory is %02X not %02X", scanline, actual.Buffer.Fetch(i), expected.Buffer.Fetch(i))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package rp2cgo2
+
+// Observer is notified by the PPU at fixed points of each frame.  All
+// methods are called on the PPU's goroutine, in the middle of emulation,
+// so they must not block.  The Frame passed to OnFrameComplete is only
+// valid for the duration of the call.
+type Observer interface {
+	OnScanlineStart(scanline uint16)
+	OnVBlank()
+	OnFrameComplete(frame *Frame)
+	OnSprite0Hit(scanline, cycle uint16)
+	OnSpriteOverflow(scanline, cycle uint16)
+}
+
+// BaseObserver implements Observer with methods that do nothing, so
+// that observers interested in only a few hooks can embed it.
+type BaseObserver struct{}
+
+func (BaseObserver) OnScanlineStart(scanline uint16)         {}
+func (BaseObserver) OnVBlank()                               {}
+func (BaseObserver) OnFrameComplete(frame *Frame)            {}
+func (BaseObserver) OnSprite0Hit(scanline, cycle uint16)     {}
+func (BaseObserver) OnSpriteOverflow(scanline, cycle uint16) {}
+
+func (ppu *RP2C02) Observe(observer Observer) {
+	ppu.Observers = append(ppu.Observers, observer)
+}
+
+func (ppu *RP2C02) notify(hook func(observer Observer)) {
+	for _, observer := range ppu.Observers {
+		hook(observer)
+	}
+}
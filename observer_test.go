@@ -0,0 +1,73 @@
+package rp2cgo2
+
+import "testing"
+
+type recorder struct {
+	BaseObserver
+	scanlines []uint16
+	vblanks   int
+	frames    []Frame
+	hits      []uint16
+	overflows []uint16
+}
+
+func (r *recorder) OnScanlineStart(scanline uint16) {
+	r.scanlines = append(r.scanlines, scanline)
+}
+
+func (r *recorder) OnVBlank() {
+	r.vblanks++
+}
+
+func (r *recorder) OnFrameComplete(frame *Frame) {
+	r.frames = append(r.frames, Frame{Number: frame.Number, Pixels: append([]uint8(nil), frame.Pixels...)})
+}
+
+func (r *recorder) OnSprite0Hit(scanline, cycle uint16) {
+	r.hits = append(r.hits, scanline)
+}
+
+func (r *recorder) OnSpriteOverflow(scanline, cycle uint16) {
+	r.overflows = append(r.overflows, scanline)
+}
+
+func TestObserver(t *testing.T) {
+	for _, renderer := range []Renderer{DotRenderer, ScanlineRenderer} {
+		ppu := newScene(renderer)
+
+		for i := uint16(0); i < 9; i++ {
+			ppu.oam.Store(i*4, 100)
+		}
+
+		r := &recorder{}
+		ppu.Observe(r)
+
+		colors := ppu.RenderFrame()
+
+		if len(r.scanlines) != NUM_SCANLINES {
+			t.Fatalf("%v: %d scanline starts not %d", renderer, len(r.scanlines), NUM_SCANLINES)
+		}
+
+		for i, scanline := range r.scanlines {
+			if scanline != uint16(i) {
+				t.Errorf("%v: Scanline start %d is %d", renderer, i, scanline)
+			}
+		}
+
+		if r.vblanks != 1 {
+			t.Errorf("%v: %d vblanks not 1", renderer, r.vblanks)
+		}
+
+		if len(r.frames) != 1 || r.frames[0].Number != 0 || len(r.frames[0].Pixels) != len(colors) {
+			t.Errorf("%v: Completed frames are wrong", renderer)
+		}
+
+		if len(r.hits) != 1 {
+			t.Errorf("%v: %d sprite 0 hits not 1", renderer, len(r.hits))
+		}
+
+		if len(r.overflows) != 1 || r.overflows[0] != 100 {
+			t.Errorf("%v: Sprite overflows are %v not [100]", renderer, r.overflows)
+		}
+	}
+}
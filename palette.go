@@ -0,0 +1,59 @@
+package rp2cgo2
+
+import "image/color"
+
+// Palette maps the 64 colors the PPU outputs to RGB.
+type Palette [64]color.RGBA
+
+// DefaultPalette is a commonly used approximation of the 2C02's NTSC
+// output.
+var DefaultPalette = Palette{
+	// 0x00
+	{84, 84, 84, 0xff}, {0, 30, 116, 0xff}, {8, 16, 144, 0xff}, {48, 0, 136, 0xff},
+	{68, 0, 100, 0xff}, {92, 0, 48, 0xff}, {84, 4, 0, 0xff}, {60, 24, 0, 0xff},
+	{32, 42, 0, 0xff}, {8, 58, 0, 0xff}, {0, 64, 0, 0xff}, {0, 60, 0, 0xff},
+	{0, 50, 60, 0xff}, {0, 0, 0, 0xff}, {0, 0, 0, 0xff}, {0, 0, 0, 0xff},
+	// 0x10
+	{152, 150, 152, 0xff}, {8, 76, 196, 0xff}, {48, 50, 236, 0xff}, {92, 30, 228, 0xff},
+	{136, 20, 176, 0xff}, {160, 20, 100, 0xff}, {152, 34, 32, 0xff}, {120, 60, 0, 0xff},
+	{84, 90, 0, 0xff}, {40, 114, 0, 0xff}, {8, 124, 0, 0xff}, {0, 118, 40, 0xff},
+	{0, 102, 120, 0xff}, {0, 0, 0, 0xff}, {0, 0, 0, 0xff}, {0, 0, 0, 0xff},
+	// 0x20
+	{236, 238, 236, 0xff}, {76, 154, 236, 0xff}, {120, 124, 236, 0xff}, {176, 98, 236, 0xff},
+	{228, 84, 236, 0xff}, {236, 88, 180, 0xff}, {236, 106, 100, 0xff}, {212, 136, 32, 0xff},
+	{160, 170, 0, 0xff}, {116, 196, 0, 0xff}, {76, 208, 32, 0xff}, {56, 204, 108, 0xff},
+	{56, 180, 204, 0xff}, {60, 60, 60, 0xff}, {0, 0, 0, 0xff}, {0, 0, 0, 0xff},
+	// 0x30
+	{236, 238, 236, 0xff}, {168, 204, 236, 0xff}, {188, 188, 236, 0xff}, {212, 178, 236, 0xff},
+	{236, 174, 236, 0xff}, {236, 174, 212, 0xff}, {236, 180, 176, 0xff}, {228, 196, 144, 0xff},
+	{204, 210, 120, 0xff}, {180, 222, 120, 0xff}, {168, 226, 144, 0xff}, {152, 226, 180, 0xff},
+	{160, 214, 228, 0xff}, {160, 162, 160, 0xff}, {0, 0, 0, 0xff}, {0, 0, 0, 0xff},
+}
+
+func (p *Palette) RGBA(index uint8) color.RGBA {
+	return p[index&0x3f]
+}
+
+// RGBPalette is the palette of the RGB PPUs fitted to Vs. System and
+// PlayChoice-10 boards, the RP2C03 and RC2C05, which output 3 bits for
+// each of red, green and blue.
+var RGBPalette = rgbPalette([64]uint16{
+	0333, 0014, 0006, 0326, 0403, 0503, 0510, 0420, 0320, 0120, 0031, 0040, 0022, 0000, 0000, 0000,
+	0555, 0036, 0027, 0407, 0507, 0704, 0700, 0630, 0430, 0140, 0040, 0053, 0044, 0000, 0000, 0000,
+	0777, 0357, 0447, 0637, 0707, 0737, 0740, 0750, 0660, 0360, 0070, 0276, 0077, 0000, 0000, 0000,
+	0777, 0567, 0657, 0757, 0747, 0755, 0764, 0772, 0773, 0572, 0473, 0276, 0467, 0000, 0000, 0000,
+})
+
+// rgbPalette expands colors given as three octal digits, red, green
+// and blue.
+func rgbPalette(colors [64]uint16) (p Palette) {
+	level := func(value uint16) uint8 {
+		return uint8((value & 0x07) * 255 / 7)
+	}
+
+	for i, c := range colors {
+		p[i] = color.RGBA{level(c >> 6), level(c >> 3), level(c), 0xff}
+	}
+
+	return
+}
@@ -1,14 +1,10 @@
 package rp2cgo2
 
 import (
-	"bufio"
-	"fmt"
 	"image"
 	"image/color"
-	"image/jpeg"
-	"os"
+	"sync"
 
-	"github.com/nwidger/m65go2"
 	"github.com/nwidger/rp2ago3"
 )
 
@@ -154,10 +150,15 @@ func (reg *Registers) Reset() {
 	reg.Data = 0x00
 }
 
+// NUM_SCANLINES and VBLANK_SCANLINE describe NTSC timing, see
+// schedules for the other regions.  START_SCANLINE is the scanline
+// PowerOn and Reset start the PPU on, so that the warm-up period lasts
+// a whole frame.  It replaces POWERUP_SCANLINE, which was 241.
 const (
 	CYCLES_PER_SCANLINE uint16 = 341
 	NUM_SCANLINES              = 262
-	POWERUP_SCANLINE           = 241
+	VBLANK_SCANLINE            = 241
+	START_SCANLINE             = 0
 )
 
 type Sprite struct {
@@ -171,9 +172,20 @@ type RP2C02 struct {
 	latch          bool
 	latchAddress   uint16
 	Output         chan []uint8
+	Frames         *FrameBuffer
 	colors         []uint8
+	last           Frame
+	lastLock       sync.Mutex
 	Registers      Registers
-	Memory         *rp2ago3.MappedMemory
+	Memory         *Memory
+	Palette        *Palette
+	RAMPattern     RAMPattern
+	RAMSeed        int64
+	SwapRegisters  bool
+	warmup         bool
+	skip           bool
+	skipped        bool
+	frameLength    uint32
 	Interrupt      func(state bool)
 	oam            *OAM
 	frame          uint16
@@ -188,45 +200,70 @@ type RP2C02 struct {
 	Cycles         chan uint16
 	quota          uint16
 	sprites        [8]Sprite
+	spriteZero     bool
+	Events         *EventLog
+	Debugger       *Debugger
+	Tracer         *Tracer
+	Accesses       *AccessMap
+	Observers      []Observer
+	Region         Region
+	schedule       *schedule
+	Renderer       Renderer
+	deferredFrom   uint16
+	deferred       uint16
+	fallback       bool
 }
 
+// NewRP2C02 creates a PPU as it was created before Config existed,
+// with four-screen memory and frames sent on Output as well as Frames,
+// so frontends receiving from Output keep working.
 func NewRP2C02(interrupt func(bool)) *RP2C02 {
-	mem := rp2ago3.NewMappedMemory(m65go2.NewBasicMemory(m65go2.DEFAULT_MEMORY_SIZE))
-	mirrors := make(map[uint16]uint16)
+	return NewRP2C02WithConfig(Config{
+		Interrupt: interrupt,
+		Mirroring: FourScreen,
+		Output:    make(chan []uint8),
+	})
+}
 
-	// Mirrored nametables
-	for i := uint16(0x3000); i <= 0x3eff; i++ {
-		mirrors[i] = i - 0x1000
-	}
+// PowerOn puts the PPU in its power-up state: every register is
+// cleared, RAM and OAM are filled according to RAMPattern and writes to
+// $2000, $2001, $2005 and $2006 are ignored until the pre-render
+// scanline of the first frame.
+func (ppu *RP2C02) PowerOn() {
+	ppu.Registers.Reset()
 
-	// Mirrored palette
-	for _, i := range []uint16{0x3f10, 0x3f14, 0x3f18, 0x3f1c} {
-		mirrors[i] = i - 0x0010
-	}
+	value := ppu.RAMPattern.values(ppu.RAMSeed)
+	ppu.Memory.fill(value)
 
-	for i := uint16(0x3f20); i <= 0x3fff; i++ {
-		mirrors[i] = i - 0x0020
+	for address := uint16(0x0000); address < 0x0100; address++ {
+		ppu.oam.Store(address, value())
 	}
 
-	mem.AddMirrors(mirrors)
-
-	return &RP2C02{
-		Output:    make(chan []uint8),
-		Memory:    mem,
-		Interrupt: interrupt,
-		oam:       NewOAM(),
-		Cycles:    make(chan uint16),
-	}
+	ppu.latchAddress = 0x0000
+	ppu.restart()
 }
 
+// Reset behaves like the console's reset button: RAM, OAM, $2002, $2003
+// and the VRAM address are preserved, everything else is cleared and
+// the same warm-up period as PowerOn applies.
 func (ppu *RP2C02) Reset() {
+	ppu.Registers.Controller = 0x00
+	ppu.Registers.Mask = 0x00
+	ppu.Registers.Scroll = 0x00
+	ppu.Registers.Data = 0x00
+
+	ppu.restart()
+}
+
+func (ppu *RP2C02) restart() {
+	ppu.catchUp()
+
 	ppu.latch = false
-	ppu.Registers.Reset()
-	ppu.Memory.Reset()
+	ppu.warmup = true
 
 	ppu.frame = 0
 	ppu.cycle = 0
-	ppu.scanline = POWERUP_SCANLINE
+	ppu.scanline = START_SCANLINE
 	ppu.quota = 0
 }
 
@@ -376,6 +413,8 @@ func (ppu *RP2C02) Mappings(which rp2ago3.Mapping) (fetch, store []uint16) {
 }
 
 func (ppu *RP2C02) Fetch(address uint16) (value uint8) {
+	ppu.catchUp()
+
 	switch address {
 	// Status
 	case 0x2002:
@@ -393,6 +432,10 @@ func (ppu *RP2C02) Fetch(address uint16) (value uint8) {
 		vramAddress := ppu.Registers.Address & 0x3fff
 		ppu.Registers.Data = ppu.Memory.Fetch(vramAddress)
 
+		if ppu.Accesses != nil {
+			ppu.Accesses.read(CPUAccess, vramAddress)
+		}
+
 		if vramAddress&0x3f00 == 0x3f00 {
 			value = ppu.Registers.Data
 		}
@@ -400,10 +443,28 @@ func (ppu *RP2C02) Fetch(address uint16) (value uint8) {
 		ppu.incrementAddress()
 	}
 
+	ppu.event(ReadEvent, address, value)
+	ppu.breakOn(BreakRegisterRead, address)
+
 	return
 }
 
 func (ppu *RP2C02) Store(address uint16, value uint8) (oldValue uint8) {
+	ppu.catchUp()
+
+	if ppu.SwapRegisters && address&0xfffe == 0x2000 {
+		address ^= 0x0001
+	}
+
+	if ppu.warmup {
+		switch address {
+		case 0x2000, 0x2001, 0x2005, 0x2006:
+			ppu.event(WriteEvent, address, value)
+			ppu.breakOn(BreakRegisterWrite, address)
+			return
+		}
+	}
+
 	switch address {
 	// Controller
 	case 0x2000:
@@ -456,13 +517,35 @@ func (ppu *RP2C02) Store(address uint16, value uint8) (oldValue uint8) {
 	// Data
 	case 0x2007:
 		oldValue = ppu.Registers.Data
+		ppu.breakOn(BreakVRAMWrite, ppu.Registers.Address&0x3fff)
 		ppu.Memory.Store(ppu.Registers.Address&0x3fff, value)
+
+		if ppu.Accesses != nil {
+			ppu.Accesses.write(CPUAccess, ppu.Registers.Address&0x3fff)
+		}
+
 		ppu.incrementAddress()
 	}
 
+	ppu.event(WriteEvent, address, value)
+	ppu.breakOn(BreakRegisterWrite, address)
+
 	return
 }
 
+func (ppu *RP2C02) event(kind EventKind, address uint16, value uint8) {
+	if ppu.Events != nil {
+		ppu.Events.record(Event{
+			Kind:     kind,
+			Frame:    ppu.frame,
+			Scanline: ppu.scanline,
+			Cycle:    ppu.cycle,
+			Address:  address,
+			Value:    value,
+		})
+	}
+}
+
 func (ppu *RP2C02) transferX() {
 	// v: ....F.. ...EDCBA = t: ....F.. ...EDCBA
 	ppu.Registers.Address = (ppu.Registers.Address & 0x7be0) | (ppu.latchAddress & 0x041f)
@@ -531,171 +614,111 @@ func (ppu *RP2C02) incrementAddress() {
 }
 
 func (ppu *RP2C02) reloadBackgroundTiles() {
-	switch ppu.cycle {
-	case 9:
-		fallthrough
-	case 17:
-		fallthrough
-	case 25:
-		fallthrough
-	case 33:
-		fallthrough
-	case 41:
-		fallthrough
-	case 49:
-		fallthrough
-	case 57:
-		fallthrough
-	case 65:
-		fallthrough
-	case 73:
-		fallthrough
-	case 81:
-		fallthrough
-	case 89:
-		fallthrough
-	case 97:
-		fallthrough
-	case 105:
-		fallthrough
-	case 113:
-		fallthrough
-	case 121:
-		fallthrough
-	case 129:
-		fallthrough
-	case 137:
-		fallthrough
-	case 145:
-		fallthrough
-	case 153:
-		fallthrough
-	case 161:
-		fallthrough
-	case 169:
-		fallthrough
-	case 177:
-		fallthrough
-	case 185:
-		fallthrough
-	case 193:
-		fallthrough
-	case 201:
-		fallthrough
-	case 209:
-		fallthrough
-	case 217:
-		fallthrough
-	case 225:
-		fallthrough
-	case 233:
-		fallthrough
-	case 241:
-		fallthrough
-	case 249:
-		fallthrough
-	case 257:
-		fallthrough
-	case 329:
-		fallthrough
-	case 337:
-		if ppu.rendering() {
-			ppu.tilesLow = (ppu.tilesLow & 0xff00) | (ppu.tilesLatch & 0x00ff)
-			ppu.tilesHigh = (ppu.tilesHigh & 0xff00) | ((ppu.tilesLatch >> 8) & 0x00ff)
-		}
-	}
+	ppu.tilesLow = (ppu.tilesLow & 0xff00) | (ppu.tilesLatch & 0x00ff)
+	ppu.tilesHigh = (ppu.tilesHigh & 0xff00) | ((ppu.tilesLatch >> 8) & 0x00ff)
+}
+
+func (ppu *RP2C02) loadPatternAddress() {
+	// 000p NNNN NNNN vvvv
+	ppu.patternAddress = ppu.controller(BackgroundPatternAddress) |
+		uint16(ppu.fetchName(ppu.Registers.Address))<<4 |
+		ppu.address(FineYScroll)
+}
+
+func (ppu *RP2C02) loadAttributeLatch() {
+	// combine 2nd X- and Y-bit of loopy_v to
+	// determine which 2-bits of AT byte to use:
+	//
+	// value = (topleft << 0) | (topright << 2) | (bottomleft << 4) | (bottomright << 6)
+	//
+	// v: .yyy NNYY YYYX XXXX|
+	//    .... .... .... ..X.|
+	// v >> 4: .... .>>> >Y..|....
+	//         .X. = 000 = 0
+	//         Y..   010 = 2
+	//               100 = 4
+	//               110 = 6
+	ppu.attributeLatch = (ppu.fetchAttribute(ppu.Registers.Address) >>
+		((ppu.Registers.Address & 0x2) | (ppu.Registers.Address >> 4 & 0x4))) & 0x03
+}
+
+func (ppu *RP2C02) loadTileLow() {
+	// Fetch color bit 0 for next 8 dots
+	ppu.tilesLatch = (ppu.tilesLatch & 0xff00) | uint16(ppu.fetch(ppu.patternAddress))
+}
+
+func (ppu *RP2C02) loadTileHigh() {
+	// Fetch color bit 1 for next 8 dots
+	ppu.tilesLatch = (ppu.tilesLatch & 0x00ff) | uint16(ppu.fetch(ppu.patternAddress|0x0008))<<8
 }
 
 func (ppu *RP2C02) shiftBackgroundTiles() {
-	if (ppu.cycle >= 2 && ppu.cycle <= 257) || (ppu.cycle >= 322 && ppu.cycle <= 337) {
-		ppu.tilesLow <<= 1
-		ppu.tilesHigh <<= 1
-		ppu.attributes = (ppu.attributes >> 2) | (uint16(ppu.attributeLatch) << 14)
-	}
+	ppu.tilesLow <<= 1
+	ppu.tilesHigh <<= 1
+	ppu.attributes = (ppu.attributes >> 2) | (uint16(ppu.attributeLatch) << 14)
 }
 
-func (ppu *RP2C02) loadSprites() {
-	index := uint8(0)
-
-	switch ppu.cycle {
-	case 319:
-		index++
-		fallthrough
-	case 311:
-		index++
-		fallthrough
-	case 303:
-		index++
-		fallthrough
-	case 295:
-		index++
-		fallthrough
-	case 287:
-		index++
-		fallthrough
-	case 279:
-		index++
-		fallthrough
-	case 272:
-		index++
-		fallthrough
-	case 257:
-		sprite := ppu.oam.Sprite(index)
-
-		ppu.sprites[index].Sprite = sprite
-		ppu.sprites[index].XPosition = ppu.sprite(sprite, XPosition)
-
-		ppu.sprites[index].TileLow = 0x00
-		ppu.sprites[index].TileHigh = 0x00
-
-		if sprite != 0xffffffff {
-			address := ppu.spriteAddress(sprite)
-			ppu.sprites[index].TileLow = ppu.Memory.Fetch(address)
-			ppu.sprites[index].TileHigh = ppu.Memory.Fetch(address | 0x0008)
-
-			reverse := func(x uint8) uint8 {
-				x = (x&0x55)<<1 | (x&0xAA)>>1
-				x = (x&0x33)<<2 | (x&0xCC)>>2
-				x = (x&0x0F)<<4 | (x&0xF0)>>4
-				return x
-			}
+func (ppu *RP2C02) loadSpriteLow(index uint8) {
+	sprite := ppu.oam.Sprite(index)
 
-			if ppu.sprite(sprite, FlipHorizontally) != 0 {
-				ppu.sprites[index].TileLow = reverse(ppu.sprites[index].TileLow)
-				ppu.sprites[index].TileHigh = reverse(ppu.sprites[index].TileHigh)
-			}
-		}
+	if index == 0 {
+		ppu.spriteZero = ppu.oam.spriteZero
 	}
+
+	ppu.sprites[index].Sprite = sprite
+	ppu.sprites[index].XPosition = ppu.sprite(sprite, XPosition)
+	ppu.sprites[index].TileLow = ppu.fetch(ppu.spriteAddress(sprite))
 }
 
-func (ppu *RP2C02) decrementSprites() {
-	for i := range ppu.sprites {
-		if ppu.sprites[i].XPosition > 0 {
-			ppu.sprites[i].XPosition--
-		}
+func (ppu *RP2C02) loadSpriteHigh(index uint8) {
+	s := &ppu.sprites[index]
+	s.TileHigh = ppu.fetch(ppu.spriteAddress(s.Sprite) | 0x0008)
+
+	// empty slots fetch tile $FF but stay transparent
+	if s.Sprite == 0xffffffff {
+		s.TileLow = 0x00
+		s.TileHigh = 0x00
+		return
 	}
-}
 
-func (ppu *RP2C02) shiftSprites() {
-	for i := range ppu.sprites {
-		if ppu.sprites[i].XPosition == 0 {
-			ppu.sprites[i].TileLow <<= 1
-			ppu.sprites[i].TileHigh <<= 1
-		}
+	if ppu.sprite(s.Sprite, FlipHorizontally) != 0 {
+		s.TileLow = reverse(s.TileLow)
+		s.TileHigh = reverse(s.TileHigh)
 	}
 }
 
+func reverse(x uint8) uint8 {
+	x = (x&0x55)<<1 | (x&0xAA)>>1
+	x = (x&0x33)<<2 | (x&0xCC)>>2
+	x = (x&0x0F)<<4 | (x&0xF0)>>4
+	return x
+}
+
 func (ppu *RP2C02) rendering() bool {
 	return ppu.mask(ShowBackground) || ppu.mask(ShowSprites)
 }
 
+func (ppu *RP2C02) fetch(address uint16) (value uint8) {
+	value = ppu.Memory.Fetch(address)
+
+	if ppu.Accesses != nil {
+		ppu.Accesses.read(RenderAccess, address)
+	}
+
+	if ppu.Tracer != nil {
+		ppu.Tracer.fetch(ppu, address, value)
+	}
+
+	return
+}
+
 func (ppu *RP2C02) fetchName(address uint16) (value uint8) {
 	//               NNii iiii iiii
 	// 0x2000 = 0010 0000 0000 0000
 	// 0x2400 = 0010 0100 0000 0000
 	// 0x2800 = 0010 1000 0000 0000
 	// 0x2c00 = 0010 1100 0000 0000
-	value = ppu.Memory.Fetch(0x2000 | address&0x0fff)
+	value = ppu.fetch(0x2000 | address&0x0fff)
 
 	return
 }
@@ -705,29 +728,30 @@ func (ppu *RP2C02) fetchAttribute(address uint16) (value uint8) {
 	//               NN = 0x0c00
 	//                      ii i = 0x0038
 	//                          jjj = 0x0007
-	value = ppu.Memory.Fetch(0x23c0 | (address & 0x0c00) | (address >> 4 & 0x0038) | (address >> 2 & 0x0007))
+	value = ppu.fetch(0x23c0 | (address & 0x0c00) | (address >> 4 & 0x0038) | (address >> 2 & 0x0007))
 
 	return
 }
 
 func (ppu *RP2C02) spriteAddress(sprite uint32) (address uint16) {
-	comparitor := (ppu.scanline - uint16(ppu.sprite(sprite, YPosition)))
+	size := ppu.controller(SpriteSize)
+	row := (ppu.scanline - uint16(ppu.sprite(sprite, YPosition))) & (size - 1)
 
 	if ppu.sprite(sprite, FlipVertically) != 0 {
-		comparitor ^= 0x000f
+		row ^= size - 1
 	}
 
-	switch ppu.controller(SpriteSize) {
+	switch size {
 	case 8:
 		address = ppu.controller(SpritePatternAddress) |
 			(uint16(ppu.sprite(sprite, TileNumber)) << 4)
 	case 16:
 		address = (uint16(ppu.sprite(sprite, TileBank)) << 12) |
 			(uint16(ppu.sprite(sprite, TileNumber)) << 5) |
-			((comparitor & 0x08) << 1)
+			((row & 0x08) << 1)
 	}
 
-	address |= comparitor & 0xf
+	address |= row & 0x07
 
 	return
 }
@@ -761,536 +785,259 @@ func (ppu *RP2C02) priorityMultiplexer(bgAddress, spriteAddress uint16, spritePr
 	return
 }
 
-func (ppu *RP2C02) renderVisibleScanline() {
-	ppu.reloadBackgroundTiles()
+// renderPixel outputs the current dot's color.  While rendering is
+// disabled the PPU outputs the backdrop color instead.
+func (ppu *RP2C02) renderPixel() {
+	address := ppu.pixel()
 
-	switch ppu.cycle {
-	// skipped on BG+odd
-	case 0:
-		if ppu.scanline == 0 && ppu.rendering() && ppu.frame&0x1 != 0 {
-			ppu.quota++
-		}
+	if !ppu.rendering() {
+		address = 0x3f00
+	}
 
-	// NT byte
-	case 1:
-		if ppu.scanline == 261 {
-			ppu.Registers.Status &^= uint8(VBlankStarted | Sprite0Hit | SpriteOverflow)
-		}
+	ppu.colors = append(ppu.colors, ppu.fetchPalette(address))
+}
 
-		fallthrough
-	case 9:
-		fallthrough
-	case 17:
-		fallthrough
-	case 25:
-		fallthrough
-	case 33:
-		fallthrough
-	case 41:
-		fallthrough
-	case 49:
-		fallthrough
-	case 57:
-		fallthrough
-	case 65:
-		fallthrough
-	case 73:
-		fallthrough
-	case 81:
-		fallthrough
-	case 89:
-		fallthrough
-	case 97:
-		fallthrough
-	case 105:
-		fallthrough
-	case 113:
-		fallthrough
-	case 121:
-		fallthrough
-	case 129:
-		fallthrough
-	case 137:
-		fallthrough
-	case 145:
-		fallthrough
-	case 153:
-		fallthrough
-	case 161:
-		fallthrough
-	case 169:
-		fallthrough
-	case 177:
-		fallthrough
-	case 185:
-		fallthrough
-	case 193:
-		fallthrough
-	case 201:
-		fallthrough
-	case 209:
-		fallthrough
-	case 217:
-		fallthrough
-	case 225:
-		fallthrough
-	case 233:
-		fallthrough
-	case 241:
-		fallthrough
-	case 249:
-		fallthrough
-	case 321:
-		fallthrough
-	case 329:
-		// 000p NNNN NNNN vvvv
-		if ppu.rendering() {
-			ppu.patternAddress = ppu.controller(BackgroundPatternAddress) |
-				uint16(ppu.fetchName(ppu.Registers.Address))<<4 |
-				ppu.address(FineYScroll)
-		}
+// fetchPalette reads a pixel's color from palette RAM, counting it as a
+// rendering read.  Palette RAM is inside the PPU rather than on its bus,
+// so unlike fetch the read is not traced.
+func (ppu *RP2C02) fetchPalette(address uint16) (value uint8) {
+	if ppu.Accesses != nil {
+		ppu.Accesses.read(RenderAccess, address)
+	}
 
-	// AT byte
-	case 3:
-		fallthrough
-	case 11:
-		fallthrough
-	case 19:
-		fallthrough
-	case 27:
-		fallthrough
-	case 35:
-		fallthrough
-	case 43:
-		fallthrough
-	case 51:
-		fallthrough
-	case 59:
-		fallthrough
-	case 67:
-		fallthrough
-	case 75:
-		fallthrough
-	case 83:
-		fallthrough
-	case 91:
-		fallthrough
-	case 99:
-		fallthrough
-	case 107:
-		fallthrough
-	case 115:
-		fallthrough
-	case 123:
-		fallthrough
-	case 131:
-		fallthrough
-	case 139:
-		fallthrough
-	case 147:
-		fallthrough
-	case 155:
-		fallthrough
-	case 163:
-		fallthrough
-	case 171:
-		fallthrough
-	case 179:
-		fallthrough
-	case 187:
-		fallthrough
-	case 195:
-		fallthrough
-	case 203:
-		fallthrough
-	case 211:
-		fallthrough
-	case 219:
-		fallthrough
-	case 227:
-		fallthrough
-	case 235:
-		fallthrough
-	case 243:
-		fallthrough
-	case 251:
-		fallthrough
-	case 323:
-		fallthrough
-	case 331:
-		// combine 2nd X- and Y-bit of loopy_v to
-		// determine which 2-bits of AT byte to use:
-		//
-		// value = (topleft << 0) | (topright << 2) | (bottomleft << 4) | (bottomright << 6)
-		//
-		// v: .yyy NNYY YYYX XXXX|
-		//    .... .... .... ..X.|
-		// v >> 4: .... .>>> >Y..|....
-		//         .X. = 000 = 0
-		//         Y..   010 = 2
-		//               100 = 4
-		//               110 = 6
-		if ppu.rendering() {
-			ppu.attributeLatch = (ppu.fetchAttribute(ppu.Registers.Address) >>
-				((ppu.Registers.Address & 0x2) | (ppu.Registers.Address >> 4 & 0x4))) & 0x03
-		}
+	return ppu.Memory.Fetch(address)
+}
 
-	// Low BG tile byte (color bit 0)
-	case 5:
-		fallthrough
-	case 13:
-		fallthrough
-	case 21:
-		fallthrough
-	case 29:
-		fallthrough
-	case 37:
-		fallthrough
-	case 45:
-		fallthrough
-	case 53:
-		fallthrough
-	case 61:
-		fallthrough
-	case 69:
-		fallthrough
-	case 77:
-		fallthrough
-	case 85:
-		fallthrough
-	case 93:
-		fallthrough
-	case 101:
-		fallthrough
-	case 109:
-		fallthrough
-	case 117:
-		fallthrough
-	case 125:
-		fallthrough
-	case 133:
-		fallthrough
-	case 141:
-		fallthrough
-	case 149:
-		fallthrough
-	case 157:
-		fallthrough
-	case 165:
-		fallthrough
-	case 173:
-		fallthrough
-	case 181:
-		fallthrough
-	case 189:
-		fallthrough
-	case 197:
-		fallthrough
-	case 205:
-		fallthrough
-	case 213:
-		fallthrough
-	case 221:
-		fallthrough
-	case 229:
-		fallthrough
-	case 237:
-		fallthrough
-	case 245:
-		fallthrough
-	case 253:
-		fallthrough
-	case 325:
-		fallthrough
-	case 333:
-		if ppu.rendering() {
-			// Fetch color bit 0 for next 8 dots
-			ppu.tilesLatch = (ppu.tilesLatch & 0xff00) | uint16(ppu.Memory.Fetch(ppu.patternAddress))
-		}
+func (ppu *RP2C02) pixel() (address uint16) {
+	spriteAddress, spritePriority, spriteZero := ppu.spritePixel()
 
-	// High BG tile byte (color bit 1)
-	case 7:
-		fallthrough
-	case 15:
-		fallthrough
-	case 23:
-		fallthrough
-	case 31:
-		fallthrough
-	case 39:
-		fallthrough
-	case 47:
-		fallthrough
-	case 55:
-		fallthrough
-	case 63:
-		fallthrough
-	case 71:
-		fallthrough
-	case 79:
-		fallthrough
-	case 87:
-		fallthrough
-	case 95:
-		fallthrough
-	case 103:
-		fallthrough
-	case 111:
-		fallthrough
-	case 119:
-		fallthrough
-	case 127:
-		fallthrough
-	case 135:
-		fallthrough
-	case 143:
-		fallthrough
-	case 151:
-		fallthrough
-	case 159:
-		fallthrough
-	case 167:
-		fallthrough
-	case 175:
-		fallthrough
-	case 183:
-		fallthrough
-	case 191:
-		fallthrough
-	case 199:
-		fallthrough
-	case 207:
-		fallthrough
-	case 215:
-		fallthrough
-	case 223:
-		fallthrough
-	case 231:
-		fallthrough
-	case 239:
-		fallthrough
-	case 247:
-		fallthrough
-	case 255:
-		fallthrough
-	case 327:
-		fallthrough
-	case 335:
-		if ppu.rendering() {
-			// Fetch color bit 1 for next 8 dots
-			ppu.tilesLatch = (ppu.tilesLatch & 0x00ff) | uint16(ppu.Memory.Fetch(ppu.patternAddress|0x0008))<<8
+	return ppu.multiplex(ppu.backgroundPixel(), spriteAddress, spritePriority, spriteZero)
+}
+
+func (ppu *RP2C02) backgroundPixel() (bgAddress uint16) {
+	bgAttribute := uint16(0)
+	bgIndex := uint16(0)
+
+	if ppu.mask(ShowBackground) && (ppu.mask(ShowBackgroundLeft) || ppu.cycle > 8) {
+		scroll := 8 + ppu.Registers.Scroll
+		bgIndex = (((ppu.tilesHigh >> scroll) & 0x0001) << 1) | ((ppu.tilesLow >> scroll) & 0x0001)
+		bgAttribute = uint16((ppu.attributes)&0x0003) << 2
+		bgAddress = uint16(0x3f00 | bgAttribute | bgIndex)
+	}
+
+	return
+}
+
+// spritePixel returns the opaque pixel of the highest priority sprite
+// on the current dot, if any, and whether that sprite is OAM sprite 0.
+// Sprite X counters count down and shift on every dot, including those
+// hidden by left clipping.
+func (ppu *RP2C02) spritePixel() (spriteAddress uint16, spritePriority uint8, spriteZero bool) {
+	if !ppu.mask(ShowSprites) {
+		return
+	}
+
+	clipped := !ppu.mask(ShowSpritesLeft) && ppu.cycle <= 8
+
+	for i := range ppu.sprites {
+		s := &ppu.sprites[i]
+
+		if s.XPosition > 0 {
+			s.XPosition--
+			continue
 		}
 
-	// inc hori(v)
-	case 8:
-		fallthrough
-	case 16:
-		fallthrough
-	case 24:
-		fallthrough
-	case 32:
-		fallthrough
-	case 40:
-		fallthrough
-	case 48:
-		fallthrough
-	case 56:
-		fallthrough
-	case 64:
-		fallthrough
-	case 72:
-		fallthrough
-	case 80:
-		fallthrough
-	case 88:
-		fallthrough
-	case 96:
-		fallthrough
-	case 104:
-		fallthrough
-	case 112:
-		fallthrough
-	case 120:
-		fallthrough
-	case 128:
-		fallthrough
-	case 136:
-		fallthrough
-	case 144:
-		fallthrough
-	case 152:
-		fallthrough
-	case 160:
-		fallthrough
-	case 168:
-		fallthrough
-	case 176:
-		fallthrough
-	case 184:
-		fallthrough
-	case 192:
-		fallthrough
-	case 200:
-		fallthrough
-	case 208:
-		fallthrough
-	case 216:
-		fallthrough
-	case 224:
-		fallthrough
-	case 232:
-		fallthrough
-	case 240:
-		fallthrough
-	case 248:
-		fallthrough
-	case 328:
-		fallthrough
-	case 336:
-		if ppu.rendering() {
-			ppu.incrementX()
+		spriteIndex := uint16((s.TileHigh>>7)&0x01)<<1 | uint16((s.TileLow>>7)&0x01)
+
+		if spriteIndex != 0 && spriteAddress == 0 && !clipped {
+			spriteAddress = 0x3f10 | uint16(ppu.sprite(s.Sprite, SpritePalette))<<2 | spriteIndex
+			spritePriority = ppu.sprite(s.Sprite, Priority)
+			spriteZero = i == 0 && ppu.spriteZero
 		}
 
-	// inc vert(v)
-	case 256:
-		if ppu.rendering() {
-			ppu.incrementX()
-			ppu.incrementY()
+		s.TileLow <<= 1
+		s.TileHigh <<= 1
+	}
+
+	return
+}
+
+func (ppu *RP2C02) multiplex(bgAddress, spriteAddress uint16, spritePriority uint8, spriteZero bool) (address uint16) {
+	address = ppu.priorityMultiplexer(bgAddress, spriteAddress, spritePriority)
+
+	// no hit at x=255, behind the background still hits
+	if spriteZero && ppu.cycle != 256 && bgAddress&0x0003 != 0 && spriteAddress&0x0003 != 0 &&
+		!ppu.status(Sprite0Hit) {
+		ppu.Registers.Status |= uint8(Sprite0Hit)
+		ppu.event(Sprite0HitEvent, 0x2002, ppu.Registers.Status)
+		ppu.breakOn(BreakSprite0Hit, 0x2002)
+
+		if ppu.Observers != nil {
+			ppu.notify(func(observer Observer) { observer.OnSprite0Hit(ppu.scanline, ppu.cycle) })
 		}
+	}
 
-	// hori(v) = hori(t)
-	case 257:
-		if ppu.rendering() {
-			ppu.transferX()
+	return
+}
+
+func (ppu *RP2C02) evaluateSprites() {
+	if ppu.oam.SpriteEvaluation(ppu.scanline, ppu.cycle, ppu.controller(SpriteSize)) {
+		ppu.spriteOverflow()
+	}
+}
+
+func (ppu *RP2C02) spriteOverflow() {
+	if !ppu.status(SpriteOverflow) {
+		ppu.Registers.Status |= uint8(SpriteOverflow)
+		ppu.event(SpriteOverflowEvent, 0x2002, ppu.Registers.Status)
+
+		if ppu.Observers != nil {
+			ppu.notify(func(observer Observer) { observer.OnSpriteOverflow(ppu.scanline, ppu.cycle) })
 		}
+	}
+}
 
-	// vert(v) = vert(t)
-	case 280:
-		fallthrough
-	case 281:
-		fallthrough
-	case 282:
-		fallthrough
-	case 283:
-		fallthrough
-	case 284:
-		fallthrough
-	case 285:
-		fallthrough
-	case 286:
-		fallthrough
-	case 287:
-		fallthrough
-	case 288:
-		fallthrough
-	case 289:
-		fallthrough
-	case 290:
-		fallthrough
-	case 291:
-		fallthrough
-	case 292:
-		fallthrough
-	case 293:
-		fallthrough
-	case 294:
-		fallthrough
-	case 295:
-		fallthrough
-	case 296:
-		fallthrough
-	case 297:
-		fallthrough
-	case 298:
-		fallthrough
-	case 299:
-		fallthrough
-	case 300:
-		fallthrough
-	case 301:
-		fallthrough
-	case 302:
-		fallthrough
-	case 303:
-		fallthrough
-	case 304:
-		if ppu.scanline == 261 && ppu.rendering() {
-			ppu.transferY()
+func (ppu *RP2C02) startVBlank() {
+	ppu.Registers.Status |= uint8(VBlankStarted)
+	ppu.breakOn(BreakVBlank, 0x2002)
+
+	if ppu.Registers.Status&uint8(VBlankStarted) != 0 &&
+		ppu.Registers.Controller&uint8(NMIOnVBlank) != 0 {
+		if ppu.Interrupt != nil {
+			ppu.Interrupt(true)
 		}
+
+		ppu.event(NMIEvent, 0x2002, ppu.Registers.Status)
 	}
 
-	if ppu.cycle >= 1 && ppu.cycle <= 256 {
-		address := uint16(0)
+	if ppu.Observers != nil {
+		ppu.notify(func(observer Observer) { observer.OnVBlank() })
+	}
+}
 
-		bgAddress := uint16(0)
-		bgAttribute := uint16(0)
-		bgIndex := uint16(0)
+func (ppu *RP2C02) tick() {
+	if ppu.Renderer == ScanlineRenderer {
+		ppu.deferDot()
+		return
+	}
+
+	ppu.step()
+}
 
-		if ppu.mask(ShowBackground) && (ppu.mask(ShowBackgroundLeft) || ppu.cycle > 8) {
-			scroll := 8 + ppu.Registers.Scroll
-			bgIndex = (((ppu.tilesHigh >> scroll) & 0x0001) << 1) | ((ppu.tilesLow >> scroll) & 0x0001)
-			bgAttribute = uint16((ppu.attributes)&0x0003) << 2
-			bgAddress = uint16(0x3f00 | bgAttribute | bgIndex)
+func (ppu *RP2C02) step() {
+	if ppu.cycle == 0 && ppu.Observers != nil {
+		ppu.notify(func(observer Observer) { observer.OnScanlineStart(ppu.scanline) })
+	}
+
+	ppu.perform(&ppu.schedule.dots[ppu.schedule.scanlines[ppu.scanline]][ppu.cycle])
+}
+
+// perform carries out the operations of a single dot.
+func (ppu *RP2C02) perform(dot *dot) {
+	ops := dot.ops
+
+	if ops == 0 {
+		return
+	}
+
+	rendering := ppu.rendering()
+
+	// dot 340 of the pre-render scanline is skipped on odd frames
+	// when rendering
+	if ops&opOddFrameSkip != 0 && rendering && ppu.frame&0x1 != 0 {
+		ppu.skip = true
+	}
+
+	if ops&opSetVBlank != 0 {
+		ppu.startVBlank()
+	}
+
+	if ops&opClearStatus != 0 {
+		ppu.Registers.Status &^= uint8(VBlankStarted | Sprite0Hit | SpriteOverflow)
+		ppu.warmup = false
+	}
+
+	if rendering {
+		if ops&opReloadTiles != 0 {
+			ppu.reloadBackgroundTiles()
 		}
 
-		spriteAddress := uint16(0)
-		spriteAttribute := uint16(0)
-		spriteIndex := uint16(0)
-		spritePriority := uint8(0)
-		spriteZero := false
-
-		if ppu.mask(ShowSprites) && (ppu.mask(ShowSpritesLeft) || ppu.cycle > 8) {
-			ppu.decrementSprites()
-
-			for i := range ppu.sprites {
-				if ppu.sprites[i].XPosition > 0 {
-					continue
-				}
-
-				switch i {
-				case 0:
-					spriteZero = true
-				default:
-					spriteZero = false
-				}
-
-				sprite := ppu.sprites[i].Sprite
-
-				spriteIndex = uint16((((ppu.sprites[i].TileHigh >> 7) & 0x0001) << 1) | ((ppu.sprites[i].TileLow >> 7) & 0x0001))
-				spriteAttribute = uint16(ppu.sprite(sprite, SpritePalette)) << 2
-				spriteAddress = uint16(0x3f10 | spriteAttribute | spriteIndex)
-				spritePriority = ppu.sprite(sprite, Priority)
-
-				if spriteIndex != 0 {
-					break
-				}
-			}
+		switch {
+		// NT byte
+		case ops&opFetchName != 0:
+			ppu.loadPatternAddress()
+
+		// AT byte
+		case ops&opFetchAttribute != 0:
+			ppu.loadAttributeLatch()
+
+		// Low BG tile byte (color bit 0)
+		case ops&opFetchTileLow != 0:
+			ppu.loadTileLow()
+
+		// High BG tile byte (color bit 1)
+		case ops&opFetchTileHigh != 0:
+			ppu.loadTileHigh()
+
+		// Garbage NT byte
+		case ops&opFetchGarbageName != 0:
+			ppu.fetchName(ppu.Registers.Address)
 
-			ppu.shiftSprites()
+		// Low sprite tile byte
+		case ops&opFetchSpriteLow != 0:
+			ppu.loadSpriteLow(dot.sprite)
+
+		// High sprite tile byte
+		case ops&opFetchSpriteHigh != 0:
+			ppu.loadSpriteHigh(dot.sprite)
 		}
 
-		address = ppu.priorityMultiplexer(bgAddress, spriteAddress, spritePriority)
+		if ops&opResetOAMAddress != 0 {
+			ppu.Registers.OAMAddress = 0
+		}
 
-		if ppu.cycle != 255 && ppu.mask(ShowBackground) && ppu.mask(ShowSprites) &&
-			bgAddress != 0x3f00 && spriteAddress != 0x3f10 && address == spriteAddress && spriteZero {
-			ppu.Registers.Status |= uint8(Sprite0Hit)
+		// inc hori(v)
+		if ops&opIncrementX != 0 {
+			ppu.incrementX()
 		}
 
-		color := ppu.Memory.Fetch(address)
+		// inc vert(v)
+		if ops&opIncrementY != 0 {
+			ppu.incrementY()
+		}
 
-		if ppu.rendering() && ppu.scanline >= 0 && ppu.scanline <= 239 {
-			ppu.colors = append(ppu.colors, color)
+		// hori(v) = hori(t)
+		if ops&opTransferX != 0 {
+			ppu.transferX()
 		}
 
-		if ppu.oam.SpriteEvaluation(ppu.scanline, ppu.cycle, ppu.controller(SpriteSize)) {
-			ppu.Registers.Status |= uint8(SpriteOverflow)
+		// vert(v) = vert(t)
+		if ops&opTransferY != 0 {
+			ppu.transferY()
 		}
 	}
 
-	ppu.shiftBackgroundTiles()
-	ppu.loadSprites()
+	if ops&opRenderPixel != 0 {
+		ppu.renderPixel()
+	}
 
-	return
+	if ops&opEvaluateSprites != 0 {
+		ppu.evaluateSprites()
+	}
+
+	if ops&opShiftTiles != 0 {
+		ppu.shiftBackgroundTiles()
+	}
+}
+
+// skipDot moves past dot 340 of the pre-render scanline when dot 339
+// decided to skip it, so the next dot executed is dot 0 of scanline 0.
+func (ppu *RP2C02) skipDot() {
+	if ppu.skip {
+		ppu.skip = false
+		ppu.skipped = true
+		ppu.cycle++
+	}
 }
 
 func (ppu *RP2C02) Execute() {
@@ -1298,43 +1045,52 @@ func (ppu *RP2C02) Execute() {
 		ppu.quota = <-ppu.Cycles
 	}
 
-	switch {
-	// visible scanlines (0-239), post-render scanline (240), pre-render scanline (261)
-	case ppu.scanline < 241 || ppu.scanline > 260:
-		ppu.renderVisibleScanline()
-	// vertical blanking scanlines (241-260)
-	default:
-		if ppu.scanline == 241 && ppu.cycle == 1 {
-			ppu.Registers.Status |= uint8(VBlankStarted)
-
-			if ppu.Registers.Status&uint8(VBlankStarted) != 0 &&
-				ppu.Registers.Controller&uint8(NMIOnVBlank) != 0 {
-				if ppu.Interrupt != nil {
-					ppu.Interrupt(true)
-				}
-			}
-		}
+	ppu.tick()
+
+	if ppu.Tracer != nil {
+		// the scanline renderer may have deferred this dot, perform it
+		// so the trace shows the state after it as the dot renderer's
+		// does
+		ppu.catchUp()
+		ppu.Tracer.dot(ppu)
+	} else {
+		ppu.deferQuota()
 	}
 
+	if ppu.Debugger != nil {
+		ppu.Debugger.dot(ppu)
+	}
+
+	ppu.skipDot()
+
 	ppu.quota--
 	if ppu.quota == 0 {
 		ppu.Cycles <- 1
 	}
 }
 
-func (ppu *RP2C02) dumpPatternTables() (left, right *image.RGBA) {
-	left = image.NewRGBA(image.Rect(0, 0, 128, 128))
-	right = image.NewRGBA(image.Rect(0, 0, 128, 128))
+// PatternTableColors are the default colors used by DumpPatternTables
+// for each of the four pixel values.
+var PatternTableColors = [4]color.RGBA{
+	color.RGBA{0, 0, 0, 255},
+	color.RGBA{203, 79, 15, 255},
+	color.RGBA{255, 155, 59, 255},
+	color.RGBA{255, 231, 163, 255},
+}
 
-	colors := [4]color.RGBA{
-		color.RGBA{0, 0, 0, 255},
-		color.RGBA{203, 79, 15, 255},
-		color.RGBA{255, 155, 59, 255},
-		color.RGBA{255, 231, 163, 255},
-	}
+// DumpPatternTables draws the pattern tables of the PPU's Memory, see
+// Memory.DumpPatternTables.
+func (ppu *RP2C02) DumpPatternTables(colors [4]color.RGBA, tall bool) (left, right *image.RGBA) {
+	return ppu.Memory.DumpPatternTables(colors, tall)
+}
 
-	x_base := 0
-	y_base := 0
+// DumpPatternTables draws the pattern tables at 0x0000 and 0x1000 as
+// two 128x128 images of 16x16 tiles.  When tall is set tiles are drawn
+// as 8x16 sprites would use them, with each even tile above the odd tile
+// following it.
+func (mem *Memory) DumpPatternTables(colors [4]color.RGBA, tall bool) (left, right *image.RGBA) {
+	left = image.NewRGBA(image.Rect(0, 0, 128, 128))
+	right = image.NewRGBA(image.Rect(0, 0, 128, 128))
 
 	ptimg := left
 
@@ -1345,53 +1101,106 @@ func (ppu *RP2C02) dumpPatternTables() (left, right *image.RGBA) {
 			ptimg = right
 		}
 
+		tile := int(address>>4) & 0xff
+		x_base := (tile & 0x0f) << 3
+		y_base := (tile >> 4) << 3
+
+		if tall {
+			x_base = ((tile >> 1) & 0x0f) << 3
+			y_base = (tile>>5)<<4 | (tile&0x01)<<3
+		}
+
 		for row := uint16(0); row <= 7; row++ {
-			low := ppu.Memory.Fetch(address + row)
-			high := ppu.Memory.Fetch(address + row + 8)
+			low := mem.Fetch(address + row)
+			high := mem.Fetch(address + row + 8)
 
 			for i := int16(7); i >= 0; i-- {
 				b := ((low >> uint16(i)) & 0x0001) | (((high >> uint16(i)) & 0x0001) << 1)
 				ptimg.Set(x_base+(8-int(i+1)), y_base+int(row), colors[b])
 			}
 		}
-
-		x_base += 8
-
-		if x_base == 128 {
-			x_base = 0
-			y_base = (y_base + 8) % 128
-		}
 	}
 
-	fo, _ := os.Create(fmt.Sprintf("left.jpg"))
-	w := bufio.NewWriter(fo)
-	jpeg.Encode(w, left, &jpeg.Options{Quality: 100})
-
-	fo, _ = os.Create(fmt.Sprintf("right.jpg"))
-	w = bufio.NewWriter(fo)
-	jpeg.Encode(w, right, &jpeg.Options{Quality: 100})
-
 	return
 }
 
 func (ppu *RP2C02) Run() {
-	// ppu.dumpPatternTables()
-
 	for {
-		ppu.colors = []uint8{}
+		if ppu.Frames != nil {
+			ppu.colors = ppu.Frames.pixels()
+		} else {
+			ppu.colors = []uint8{}
+		}
 
-		for ; ppu.scanline < NUM_SCANLINES; ppu.scanline++ {
+		ppu.schedule = schedules[ppu.Region]
+
+		for ; ppu.scanline < uint16(len(ppu.schedule.scanlines)); ppu.scanline++ {
 			for ppu.cycle = 0; ppu.cycle < CYCLES_PER_SCANLINE; ppu.cycle++ {
 				ppu.Execute()
 			}
 		}
 
 		if ppu.rendering() {
-			ppu.Output <- ppu.colors
-			<-ppu.Output
+			ppu.present()
 		}
 
-		ppu.scanline = 0
-		ppu.frame++
+		ppu.endFrame()
+	}
+}
+
+// present hands the completed frame to Frames without blocking.  If
+// Output is set the frame is also sent on it and the PPU waits for the
+// consumer to reply as before.
+func (ppu *RP2C02) present() {
+	ppu.padFrame()
+
+	if ppu.Frames != nil {
+		ppu.Frames.publish(ppu.frame, ppu.colors)
+	}
+
+	if ppu.Output != nil {
+		colors := ppu.colors
+
+		if ppu.Frames != nil {
+			colors = append([]uint8(nil), colors...)
+		}
+
+		ppu.Output <- colors
+		<-ppu.Output
+	}
+}
+
+func (ppu *RP2C02) endFrame() {
+	ppu.padFrame()
+	ppu.keepLast()
+
+	if ppu.Observers != nil {
+		frame := &Frame{Number: ppu.frame, Pixels: ppu.colors}
+		ppu.notify(func(observer Observer) { observer.OnFrameComplete(frame) })
+	}
+
+	if ppu.Events != nil {
+		ppu.Events.endFrame()
+	}
+
+	if ppu.Accesses != nil {
+		ppu.Accesses.endFrame()
 	}
+
+	ppu.frameLength = uint32(len(ppu.schedule.scanlines)) * uint32(CYCLES_PER_SCANLINE)
+
+	if ppu.skipped {
+		ppu.frameLength--
+		ppu.skipped = false
+	}
+
+	ppu.scanline = 0
+	ppu.frame++
+}
+
+// FrameLength returns the number of dots in the last completed frame.
+// NTSC frames are one dot shorter on odd frames with rendering enabled,
+// averaging 89341.5 dots.
+func (ppu *RP2C02) FrameLength() uint32 {
+	return ppu.frameLength
 }
@@ -1,6 +1,11 @@
 package rp2cgo2
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nwidger/m65go2"
+)
 
 func TestController(t *testing.T) {
 	ppu := NewRP2C02(nil)
@@ -695,3 +700,338 @@ func TestTransferY(t *testing.T) {
 		t.Error("Registers is not 0x7be0")
 	}
 }
+
+func TestPowerOn(t *testing.T) {
+	ppu := NewRP2C02(nil)
+	ppu.RAMPattern = OnesRAM
+	ppu.Registers.Controller = 0xff
+	ppu.PowerOn()
+
+	if ppu.Registers.Controller != 0x00 {
+		t.Errorf("Register is %02X not 0x00", ppu.Registers.Controller)
+	}
+
+	if ppu.Memory.Fetch(0x2123) != 0xff || ppu.Memory.Fetch(0x0123) != 0xff {
+		t.Error("Memory is not 0xff")
+	}
+
+	if ppu.Memory.Fetch(0x3f01) != 0x3f {
+		t.Errorf("Palette is %02X not 0x3f", ppu.Memory.Fetch(0x3f01))
+	}
+
+	if ppu.oam.Fetch(0x0010) != 0xff {
+		t.Errorf("OAM is %02X not 0xff", ppu.oam.Fetch(0x0010))
+	}
+
+	ppu.Store(0x2000, 0x80)
+	ppu.Store(0x2001, 0x1e)
+	ppu.Store(0x2006, 0x21)
+
+	if ppu.Registers.Controller != 0x00 || ppu.Registers.Mask != 0x00 || ppu.latch {
+		t.Error("Writes during warm-up were not ignored")
+	}
+
+	ppu.Store(0x2003, 0x10)
+
+	if ppu.Registers.OAMAddress != 0x10 {
+		t.Errorf("Register is %02X not 0x10", ppu.Registers.OAMAddress)
+	}
+
+	ppu.RenderFrame()
+	ppu.Store(0x2000, 0x80)
+
+	if ppu.Registers.Controller != 0x80 {
+		t.Errorf("Register is %02X not 0x80", ppu.Registers.Controller)
+	}
+}
+
+func TestPowerOnRandom(t *testing.T) {
+	a := NewRP2C02(nil)
+	a.RAMPattern = RandomRAM
+	a.RAMSeed = 42
+	a.PowerOn()
+
+	b := NewRP2C02(nil)
+	b.RAMPattern = RandomRAM
+	b.RAMSeed = 42
+	b.PowerOn()
+
+	if *a.Memory != *b.Memory {
+		t.Error("Memory differs for the same seed")
+	}
+
+	b.RAMSeed = 43
+	b.PowerOn()
+
+	if *a.Memory == *b.Memory {
+		t.Error("Memory is the same for different seeds")
+	}
+}
+
+func TestReset(t *testing.T) {
+	ppu := NewRP2C02(nil)
+	ppu.Memory.Store(0x2000, 0xaa)
+	ppu.Store(0x2000, 0x80)
+	ppu.Store(0x2003, 0x10)
+	ppu.Store(0x2006, 0x21)
+	ppu.Registers.Status = uint8(VBlankStarted)
+
+	ppu.Reset()
+
+	if ppu.Memory.Fetch(0x2000) != 0xaa {
+		t.Errorf("Memory is %02X not 0xaa", ppu.Memory.Fetch(0x2000))
+	}
+
+	if ppu.Registers.Controller != 0x00 {
+		t.Errorf("Register is %02X not 0x00", ppu.Registers.Controller)
+	}
+
+	if ppu.Registers.OAMAddress != 0x10 || ppu.Registers.Status != uint8(VBlankStarted) {
+		t.Error("OAMAddress and Status were not preserved")
+	}
+
+	if ppu.latch {
+		t.Error("Latch was not cleared")
+	}
+
+	ppu.Store(0x2000, 0x80)
+
+	if ppu.Registers.Controller != 0x00 {
+		t.Errorf("Register is %02X not 0x00", ppu.Registers.Controller)
+	}
+}
+
+type hitRecorder struct {
+	BaseObserver
+	hits [][2]uint16
+}
+
+func (r *hitRecorder) OnSprite0Hit(scanline, cycle uint16) {
+	r.hits = append(r.hits, [2]uint16{scanline, cycle})
+}
+
+func TestSprite0Hit(t *testing.T) {
+	all := uint8(ShowBackground | ShowSprites | ShowBackgroundLeft | ShowSpritesLeft)
+
+	for _, test := range []struct {
+		name       string
+		mask       uint8
+		controller uint8
+		background uint8
+		sprites    [][4]uint8
+		hit        bool
+		scanline   uint16
+		cycle      uint16
+	}{
+		{"basic", all, 0, 1, [][4]uint8{{50, 1, 0x00, 100}}, true, 51, 101},
+		{"behind background", all, 0, 1, [][4]uint8{{50, 1, 0x20, 100}}, true, 51, 101},
+		{"transparent background", all, 0, 0, [][4]uint8{{50, 1, 0x00, 100}}, false, 0, 0},
+		{"not sprite 0", all, 0, 1, [][4]uint8{{0xf0, 1, 0x00, 100}, {50, 1, 0x00, 100}}, false, 0, 0},
+		{"sprite 0 in slot 1", all, 0, 1, [][4]uint8{{50, 1, 0x00, 100}, {50, 1, 0x00, 10}}, true, 51, 101},
+		{"x 255", all, 0, 1, [][4]uint8{{50, 1, 0x00, 255}}, false, 0, 0},
+		{"x 254", all, 0, 1, [][4]uint8{{50, 1, 0x00, 254}}, true, 51, 255},
+		{"sprites left clip", all &^ uint8(ShowSpritesLeft), 0, 1, [][4]uint8{{50, 1, 0x00, 0}}, false, 0, 0},
+		{"background left clip", all &^ uint8(ShowBackgroundLeft), 0, 1, [][4]uint8{{50, 1, 0x00, 4}}, true, 51, 9},
+		{"sprites left clip partial", all &^ uint8(ShowSpritesLeft), 0, 1, [][4]uint8{{50, 1, 0x00, 4}}, true, 51, 9},
+		{"sprites hidden", all &^ uint8(ShowSprites), 0, 1, [][4]uint8{{50, 1, 0x00, 100}}, false, 0, 0},
+		{"8x8 transparent tile", all, 0, 1, [][4]uint8{{50, 0, 0x00, 100}}, false, 0, 0},
+		{"8x16 bottom half", all, uint8(SpriteSize), 1, [][4]uint8{{50, 0, 0x00, 100}}, true, 59, 101},
+		{"8x16 flipped", all, uint8(SpriteSize), 1, [][4]uint8{{50, 0, 0x80, 100}}, true, 51, 101},
+		{"8x16 pattern table", all, uint8(SpriteSize), 1, [][4]uint8{{50, 1, 0x00, 100}}, false, 0, 0},
+		{"8x16 x 255", all, uint8(SpriteSize), 1, [][4]uint8{{50, 0, 0x00, 255}}, false, 0, 0},
+		{"8x16 left clip", all &^ uint8(ShowBackgroundLeft), uint8(SpriteSize), 1, [][4]uint8{{50, 0, 0x00, 0}}, false, 0, 0},
+		{"8x16 left clip partial", all &^ uint8(ShowBackgroundLeft), uint8(SpriteSize), 1, [][4]uint8{{50, 0, 0x00, 4}}, true, 59, 9},
+	} {
+		for _, renderer := range []Renderer{DotRenderer, ScanlineRenderer} {
+			ppu := NewRP2C02(nil)
+			ppu.Renderer = renderer
+
+			for address := uint16(0x0010); address < 0x0018; address++ {
+				ppu.Memory.Store(address, 0xff)
+			}
+
+			for address := uint16(0x2000); address < 0x2800; address++ {
+				ppu.Memory.Store(address, test.background)
+			}
+
+			for address := uint16(0x0000); address < 0x0100; address++ {
+				ppu.oam.Store(address, 0xff)
+			}
+
+			for i, sprite := range test.sprites {
+				for j, value := range sprite {
+					ppu.oam.Store(uint16(i*4+j), value)
+				}
+			}
+
+			ppu.Registers.Mask = test.mask
+			ppu.Registers.Controller = test.controller
+
+			r := &hitRecorder{}
+			ppu.Observe(r)
+			ppu.RenderFrame()
+
+			switch {
+			case !test.hit && len(r.hits) != 0:
+				t.Errorf("%s, %v: Sprite 0 hit at %v", test.name, renderer, r.hits)
+			case test.hit && len(r.hits) != 1:
+				t.Errorf("%s, %v: %d sprite 0 hits not 1", test.name, renderer, len(r.hits))
+			case test.hit && r.hits[0] != [2]uint16{test.scanline, test.cycle}:
+				t.Errorf("%s, %v: Sprite 0 hit at %v not %d,%d", test.name, renderer, r.hits[0], test.scanline, test.cycle)
+			}
+		}
+	}
+}
+
+type fetchRecorder struct {
+	*m65go2.BasicMemory
+	addresses []uint16
+}
+
+func (mem *fetchRecorder) Fetch(address uint16) (value uint8) {
+	mem.addresses = append(mem.addresses, address)
+	return mem.BasicMemory.Fetch(address)
+}
+
+func TestSpriteFetches(t *testing.T) {
+	patterns := &fetchRecorder{BasicMemory: m65go2.NewBasicMemory(0x2000)}
+	patterns.Store(0x1234, 0x81)
+	patterns.Store(0x123c, 0x7e)
+
+	ppu := NewRP2C02(nil)
+	ppu.Memory.Patterns = patterns
+	ppu.Registers.Controller = uint8(SpritePatternAddress)
+	ppu.Registers.Mask = uint8(ShowBackground | ShowSprites)
+
+	for address := uint16(0x0000); address < 0x0100; address++ {
+		ppu.oam.Store(address, 0xff)
+	}
+
+	// tile 0x23, row 4, flipped horizontally
+	ppu.oam.Store(0x0010, 20)
+	ppu.oam.Store(0x0011, 0x23)
+	ppu.oam.Store(0x0012, 0x40)
+	ppu.oam.Store(0x0013, 0x30)
+
+	ppu.scanline = 24
+	ppu.quota = 0xffff
+
+	for ppu.cycle = 0; ppu.cycle <= 256; ppu.cycle++ {
+		ppu.Execute()
+	}
+
+	patterns.addresses = nil
+
+	for ; ppu.cycle <= 320; ppu.cycle++ {
+		ppu.Execute()
+	}
+
+	expected := []uint16{0x1234, 0x123c}
+
+	for slot := 1; slot < 8; slot++ {
+		// empty slots fetch tile $FF, row 1 flipped vertically
+		expected = append(expected, 0x1ff6, 0x1ffe)
+	}
+
+	if len(patterns.addresses) != len(expected) {
+		t.Fatalf("Fetched %04X not %04X", patterns.addresses, expected)
+	}
+
+	for i := range expected {
+		if patterns.addresses[i] != expected[i] {
+			t.Errorf("Fetch %d is %04X not %04X", i, patterns.addresses[i], expected[i])
+		}
+	}
+
+	if ppu.sprites[0].TileLow != 0x81 || ppu.sprites[0].TileHigh != 0x7e || ppu.sprites[0].XPosition != 0x30 {
+		t.Errorf("Sprite is %+v", ppu.sprites[0])
+	}
+
+	if ppu.sprites[1].TileLow|ppu.sprites[1].TileHigh != 0x00 {
+		t.Errorf("Empty sprite is %+v", ppu.sprites[1])
+	}
+}
+
+func TestOddFrameSkip(t *testing.T) {
+	for _, test := range []struct {
+		region  Region
+		mask    uint8
+		lengths [4]uint32
+	}{
+		{NTSC, uint8(ShowBackground), [4]uint32{89342, 89341, 89342, 89341}},
+		{NTSC, 0x00, [4]uint32{89342, 89342, 89342, 89342}},
+		{PAL, uint8(ShowBackground), [4]uint32{106392, 106392, 106392, 106392}},
+	} {
+		ppu := NewRP2C02WithConfig(Config{Region: test.region})
+		ppu.Registers.Mask = test.mask
+
+		for i, length := range test.lengths {
+			ppu.RenderFrame()
+
+			if ppu.FrameLength() != length {
+				t.Errorf("%v frame %d is %d dots not %d", test.region, i, ppu.FrameLength(), length)
+			}
+		}
+	}
+}
+
+func TestOddFrameSkipExecute(t *testing.T) {
+	ppu := NewRP2C02(nil)
+	ppu.Registers.Mask = uint8(ShowBackground)
+
+	for frame, length := range []int{89342, 89341} {
+		dots := 0
+
+		for ppu.scanline = 0; ppu.scanline < NUM_SCANLINES; ppu.scanline++ {
+			ppu.quota = CYCLES_PER_SCANLINE + 1
+
+			for ppu.cycle = 0; ppu.cycle < CYCLES_PER_SCANLINE; ppu.cycle++ {
+				ppu.Execute()
+				dots++
+			}
+		}
+
+		ppu.endFrame()
+
+		if dots != length {
+			t.Errorf("Frame %d executed %d dots not %d", frame, dots, length)
+		}
+	}
+}
+
+func TestDumpPatternTables(t *testing.T) {
+	ppu := NewRP2C02(nil)
+
+	// tile 1 row 0 pixel 0 is 1, tile 0x101 row 7 pixel 7 is 3
+	ppu.Memory.Store(0x0010, 0x80)
+	ppu.Memory.Store(0x1017, 0x01)
+	ppu.Memory.Store(0x101f, 0x01)
+
+	left, right := ppu.DumpPatternTables(PatternTableColors, false)
+
+	if left.RGBAAt(8, 0) != PatternTableColors[1] || left.RGBAAt(0, 0) != PatternTableColors[0] {
+		t.Errorf("Left pixel is %v not %v", left.RGBAAt(8, 0), PatternTableColors[1])
+	}
+
+	if right.RGBAAt(15, 7) != PatternTableColors[3] {
+		t.Errorf("Right pixel is %v not %v", right.RGBAAt(15, 7), PatternTableColors[3])
+	}
+
+	left, right = ppu.DumpPatternTables(PatternTableColors, true)
+
+	if left.RGBAAt(0, 8) != PatternTableColors[1] {
+		t.Errorf("Left pixel is %v not %v", left.RGBAAt(0, 8), PatternTableColors[1])
+	}
+
+	if right.RGBAAt(7, 15) != PatternTableColors[3] {
+		t.Errorf("Right pixel is %v not %v", right.RGBAAt(7, 15), PatternTableColors[3])
+	}
+
+	// a Memory on its own draws the same tables
+	mem := &Memory{}
+	ppu.Memory.CopyTo(mem)
+
+	if copied, _ := mem.DumpPatternTables(PatternTableColors, true); !bytes.Equal(copied.Pix, left.Pix) {
+		t.Error("Memory's pattern tables differ from the PPU's")
+	}
+}
@@ -0,0 +1,87 @@
+package record
+
+import (
+	"image"
+	"image/gif"
+	"io"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+// GIFRecorder collects frames into an animated GIF using the 64 NES
+// colors as the GIF's palette.  Frames are held in memory until Close
+// writes the GIF.
+//
+// Only every Every'th frame is kept, as many viewers slow down frames
+// shorter than 2/100ths of a second.  Frame delays are rounded so that
+// the animation keeps the region's exact frame rate overall.
+type GIFRecorder struct {
+	rp2cgo2.BaseObserver
+	w           io.Writer
+	palette     *rp2cgo2.Palette
+	numerator   int
+	denominator int
+	Every       int
+	Options     *rp2cgo2.ScreenshotOptions
+	frames      int
+	elapsed     int
+	gif         gif.GIF
+}
+
+func NewGIFRecorder(w io.Writer, region rp2cgo2.Region, palette *rp2cgo2.Palette) *GIFRecorder {
+	numerator, denominator := FrameRate(region)
+
+	return &GIFRecorder{
+		w:           w,
+		palette:     palette,
+		numerator:   numerator,
+		denominator: denominator,
+		Every:       2,
+	}
+}
+
+// centiseconds returns the time at which frame n is shown in 1/100ths
+// of a second, rounded to the nearest.
+func (r *GIFRecorder) centiseconds(n int) int {
+	return int((int64(n)*int64(r.denominator)*100*2 + int64(r.numerator)) / (int64(r.numerator) * 2))
+}
+
+func (r *GIFRecorder) OnFrameComplete(frame *rp2cgo2.Frame) {
+	every := r.Every
+
+	if every < 1 {
+		every = 1
+	}
+
+	if r.frames%every == 0 {
+		r.gif.Image = append(r.gif.Image, frame.Image(r.palette, r.Options))
+		r.gif.Delay = append(r.gif.Delay, 0)
+	}
+
+	r.frames++
+
+	if n := len(r.gif.Delay); n != 0 && r.frames%every == 0 {
+		now := r.centiseconds(r.frames)
+		r.gif.Delay[n-1] = now - r.elapsed
+		r.elapsed = now
+	}
+}
+
+// Close writes the recorded frames as a looping GIF.
+func (r *GIFRecorder) Close() error {
+	if len(r.gif.Image) == 0 {
+		return nil
+	}
+
+	if n := len(r.gif.Delay); r.gif.Delay[n-1] == 0 {
+		r.gif.Delay[n-1] = r.centiseconds(r.frames) - r.elapsed
+	}
+
+	r.gif.Config = image.Config{
+		ColorModel: r.gif.Image[0].Palette,
+		Width:      r.gif.Image[0].Rect.Dx(),
+		Height:     r.gif.Image[0].Rect.Dy(),
+	}
+
+	return gif.EncodeAll(r.w, &r.gif)
+}
@@ -0,0 +1,23 @@
+// Package record writes the frames completed by an RP2C02 to animated
+// GIF or YUV4MPEG2 files.  Recorders are Observers, attached with
+// RP2C02.Observe.
+package record
+
+import "github.com/nwidger/rp2cgo2"
+
+// FrameRate returns the exact frame rate of region as a fraction:
+// 39375000/655171 (60.0988 Hz) for NTSC and 10640685/212784 (50.007 Hz)
+// for PAL and Dendy.
+func FrameRate(region rp2cgo2.Region) (numerator, denominator int) {
+	switch region {
+	case rp2cgo2.PAL, rp2cgo2.Dendy:
+		return 10640685, 212784
+	}
+
+	return 39375000, 655171
+}
+
+var (
+	_ rp2cgo2.Observer = &GIFRecorder{}
+	_ rp2cgo2.Observer = &Y4MRecorder{}
+)
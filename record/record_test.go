@@ -0,0 +1,136 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"image/gif"
+	"io"
+	"testing"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+func frames(n int) (frames []*rp2cgo2.Frame) {
+	for i := 0; i < n; i++ {
+		pixels := make([]uint8, 256*240)
+
+		for j := range pixels {
+			pixels[j] = uint8(i+j) & 0x3f
+		}
+
+		frames = append(frames, &rp2cgo2.Frame{Number: uint16(i), Pixels: pixels})
+	}
+
+	return
+}
+
+func TestFrameRate(t *testing.T) {
+	for _, test := range []struct {
+		region rp2cgo2.Region
+		rate   float64
+	}{
+		{rp2cgo2.NTSC, 60.0988},
+		{rp2cgo2.PAL, 50.0070},
+		{rp2cgo2.Dendy, 50.0070},
+	} {
+		numerator, denominator := FrameRate(test.region)
+
+		if rate := float64(numerator) / float64(denominator); rate < test.rate-0.0001 || rate > test.rate+0.0001 {
+			t.Errorf("%v frame rate is %f not %f", test.region, rate, test.rate)
+		}
+	}
+}
+
+func TestGIFRecorder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewGIFRecorder(buf, rp2cgo2.NTSC, &rp2cgo2.DefaultPalette)
+
+	for _, frame := range frames(61) {
+		r.OnFrameComplete(frame)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := gif.DecodeAll(buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.Image) != 31 {
+		t.Fatalf("GIF has %d frames not 31", len(g.Image))
+	}
+
+	total := 0
+
+	for i, delay := range g.Delay {
+		// the last frame is only shown for the one frame recorded
+		if (delay < 3 || delay > 4) && i != len(g.Delay)-1 {
+			t.Errorf("Delay is %d", delay)
+		}
+
+		total += delay
+	}
+
+	// 61 frames at 60.0988 Hz
+	if total != 101 {
+		t.Errorf("GIF lasts %d/100ths of a second not 101", total)
+	}
+
+	if len(g.Image[0].Palette) != 64 || g.Image[1].ColorIndexAt(0, 0) != 2 {
+		t.Error("GIF does not use the NES palette")
+	}
+}
+
+func TestY4MRecorder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewY4MRecorder(buf, rp2cgo2.PAL, &rp2cgo2.DefaultPalette)
+	r.Options = &rp2cgo2.ScreenshotOptions{CropOverscan: true}
+
+	for _, frame := range frames(3) {
+		r.OnFrameComplete(frame)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(buf)
+	header, _ := br.ReadString('\n')
+
+	if header != "YUV4MPEG2 W256 H224 F10640685:212784 Ip A8:7 C420jpeg\n" {
+		t.Errorf("Header is %q", header)
+	}
+
+	size := 256*224 + 2*128*112
+
+	for i := 0; i < 3; i++ {
+		if line, _ := br.ReadString('\n'); line != "FRAME\n" {
+			t.Fatalf("Frame %d header is %q", i, line)
+		}
+
+		frame := make([]uint8, size)
+
+		if n, _ := br.Read(frame[:1]); n != 1 {
+			t.Fatalf("Frame %d is missing", i)
+		}
+
+		if _, err := io.ReadFull(br, frame[1:]); err != nil {
+			t.Fatalf("Frame %d is short: %v", i, err)
+		}
+	}
+
+	if _, err := br.ReadByte(); err == nil {
+		t.Error("Stream has trailing data")
+	}
+
+	if r.yuv[0x0f] != [3]uint8{16, 128, 128} {
+		t.Errorf("Black is %v not [16 128 128]", r.yuv[0x0f])
+	}
+
+	if r.yuv[0x30][0] != 220 {
+		t.Errorf("White is %v not 220", r.yuv[0x30][0])
+	}
+}
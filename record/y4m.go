@@ -0,0 +1,117 @@
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/nwidger/rp2cgo2"
+)
+
+// Y4MRecorder writes each frame uncompressed to a YUV4MPEG2 stream in
+// 4:2:0 with BT.601 limited range colors, suitable for piping into a
+// video encoder.  The stream header gives the region's exact frame rate
+// and the NES's 8:7 pixel aspect ratio.
+type Y4MRecorder struct {
+	rp2cgo2.BaseObserver
+	w           *bufio.Writer
+	numerator   int
+	denominator int
+	Options     *rp2cgo2.ScreenshotOptions
+	yuv         [64][3]uint8
+	started     bool
+	y, cb, cr   []uint8
+	err         error
+}
+
+// scale divides a BT.601 coefficient sum by 256000, rounding to the
+// nearest.
+func scale(v int) int {
+	if v < 0 {
+		return -((-v + 128000) / 256000)
+	}
+
+	return (v + 128000) / 256000
+}
+
+func NewY4MRecorder(w io.Writer, region rp2cgo2.Region, palette *rp2cgo2.Palette) *Y4MRecorder {
+	numerator, denominator := FrameRate(region)
+
+	r := &Y4MRecorder{
+		w:           bufio.NewWriter(w),
+		numerator:   numerator,
+		denominator: denominator,
+	}
+
+	for i, c := range palette {
+		R, G, B := int(c.R), int(c.G), int(c.B)
+
+		r.yuv[i] = [3]uint8{
+			uint8(16 + scale(65738*R+129057*G+25064*B)),
+			uint8(128 + scale(-37945*R-74494*G+112439*B)),
+			uint8(128 + scale(112439*R-94154*G-18285*B)),
+		}
+	}
+
+	return r
+}
+
+func (r *Y4MRecorder) OnFrameComplete(frame *rp2cgo2.Frame) {
+	if r.err != nil {
+		return
+	}
+
+	img := frame.Image(&rp2cgo2.Palette{}, r.Options)
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+
+	if !r.started {
+		_, r.err = fmt.Fprintf(r.w, "YUV4MPEG2 W%d H%d F%d:%d Ip A8:7 C420jpeg\n", width, height, r.numerator, r.denominator)
+		r.started = true
+
+		r.y = make([]uint8, width*height)
+		r.cb = make([]uint8, ((width+1)/2)*((height+1)/2))
+		r.cr = make([]uint8, len(r.cb))
+	}
+
+	for i, index := range img.Pix {
+		r.y[i] = r.yuv[index][0]
+	}
+
+	cw := (width + 1) / 2
+
+	for cy := 0; cy < (height+1)/2; cy++ {
+		for cx := 0; cx < cw; cx++ {
+			cb, cr, n := 0, 0, 0
+
+			for _, p := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				x, y := cx*2+p[0], cy*2+p[1]
+
+				if x < width && y < height {
+					yuv := r.yuv[img.Pix[y*img.Stride+x]]
+					cb += int(yuv[1])
+					cr += int(yuv[2])
+					n++
+				}
+			}
+
+			r.cb[cy*cw+cx] = uint8((cb + n/2) / n)
+			r.cr[cy*cw+cx] = uint8((cr + n/2) / n)
+		}
+	}
+
+	for _, plane := range [][]uint8{[]uint8("FRAME\n"), r.y, r.cb, r.cr} {
+		if r.err == nil {
+			_, r.err = r.w.Write(plane)
+		}
+	}
+}
+
+// Close flushes the stream and returns the first error encountered
+// while writing it.
+func (r *Y4MRecorder) Close() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return r.w.Flush()
+}
@@ -0,0 +1,404 @@
+package rp2cgo2
+
+type Renderer uint8
+
+const (
+	DotRenderer Renderer = iota
+	ScanlineRenderer
+)
+
+func (r Renderer) String() string {
+	switch r {
+	case DotRenderer:
+		return "Dot"
+	case ScanlineRenderer:
+		return "Scanline"
+	}
+
+	return "Unknown"
+}
+
+// deferDot is used in place of step by the scanline renderer.  Dots 1
+// through 340 of visible scanlines are not performed as they are
+// executed but deferred until the end of the scanline, where dots 1
+// through 256 are drawn at once by renderScanline.  Any CPU access to a
+// PPU register while dots are deferred first catches up by performing
+// the deferred dots one at a time, and the remainder of the scanline
+// falls back to being performed dot by dot.  Nothing is deferred while
+// the debugger has a breakpoint set or is single-stepping.
+func (ppu *RP2C02) deferDot() {
+	if ppu.cycle == 0 {
+		ppu.fallback = false
+	}
+
+	// breakpoints are checked as each dot is stepped, so a deferred dot
+	// would report a sprite 0 hit or register access late
+	if ppu.Debugger != nil && ppu.Debugger.armed() {
+		ppu.catchUp()
+		ppu.fallback = true
+	}
+
+	if ppu.cycle == 0 || ppu.fallback || ppu.schedule.scanlines[ppu.scanline] != visibleScanline {
+		ppu.step()
+		return
+	}
+
+	if ppu.deferred == 0 {
+		ppu.deferredFrom = ppu.cycle
+	}
+
+	ppu.deferred++
+
+	if ppu.cycle == CYCLES_PER_SCANLINE-1 {
+		ppu.flush()
+	}
+}
+
+// deferQuota defers, in one go, the dots following a deferred dot up to
+// the end of the scanline or the last dot of the CPU's quota, whichever
+// comes first.  The CPU does not run until its quota is used up, so no
+// register access can fall between these dots.  The last dot of the
+// quota is left to Execute so the CPU is still answered as before.
+func (ppu *RP2C02) deferQuota() {
+	if ppu.deferred == 0 || ppu.quota < 2 {
+		return
+	}
+
+	n := CYCLES_PER_SCANLINE - 1 - ppu.cycle
+
+	if n > ppu.quota-2 {
+		n = ppu.quota - 2
+	}
+
+	ppu.deferred += n
+	ppu.cycle += n
+	ppu.quota -= n
+
+	if ppu.cycle == CYCLES_PER_SCANLINE-1 {
+		ppu.flush()
+	}
+}
+
+func (ppu *RP2C02) flush() {
+	cycle := ppu.cycle
+	from, to := ppu.deferredFrom, ppu.deferredFrom+ppu.deferred
+
+	ppu.deferred = 0
+
+	if from == 1 && to > 256 {
+		ppu.renderScanline()
+		from = 257
+	}
+
+	if from == 257 && to == CYCLES_PER_SCANLINE {
+		ppu.renderTail()
+		from = to
+	}
+
+	for ppu.cycle = from; ppu.cycle < to; ppu.cycle++ {
+		ppu.step()
+	}
+
+	ppu.cycle = cycle
+}
+
+// RenderFrame renders the remainder of the current frame using the
+// selected Renderer and returns its colors.  Unlike Run it does not
+// wait on Cycles, so it is only suitable when no CPU is attached, and
+// it does not call the Tracer or Debugger.
+func (ppu *RP2C02) RenderFrame() []uint8 {
+	ppu.colors = make([]uint8, 0, 256*240)
+	ppu.schedule = schedules[ppu.Region]
+
+	for ; ppu.scanline < uint16(len(ppu.schedule.scanlines)); ppu.scanline++ {
+		ppu.cycle = 0
+
+		if ppu.Renderer == ScanlineRenderer && ppu.schedule.scanlines[ppu.scanline] == visibleScanline {
+			ppu.step()
+			ppu.renderScanline()
+			ppu.renderTail()
+			ppu.cycle = CYCLES_PER_SCANLINE
+		}
+
+		for ; ppu.cycle < CYCLES_PER_SCANLINE; ppu.cycle++ {
+			ppu.step()
+			ppu.skipDot()
+		}
+	}
+
+	ppu.endFrame()
+
+	return ppu.colors
+}
+
+func (ppu *RP2C02) catchUp() {
+	if ppu.deferred > 0 {
+		ppu.flush()
+		ppu.fallback = true
+	}
+}
+
+type spriteDot struct {
+	address  uint16
+	priority uint8
+	zero     bool
+}
+
+// spriteLine computes, for each of dots 1 through 256, the opaque sprite
+// pixel spritePixel would produce on that dot, or the zero value if no
+// sprite is opaque there.
+func (ppu *RP2C02) spriteLine(line *[257]spriteDot) {
+	if !ppu.mask(ShowSprites) {
+		return
+	}
+
+	first := uint16(1)
+
+	if !ppu.mask(ShowSpritesLeft) {
+		first = 9
+	}
+
+	// lower slots take priority so are drawn last
+	for i := len(ppu.sprites) - 1; i >= 0; i-- {
+		s := &ppu.sprites[i]
+
+		if s.TileLow|s.TileHigh == 0 {
+			continue
+		}
+
+		start := 1 + uint16(s.XPosition)
+		attribute := uint16(ppu.sprite(s.Sprite, SpritePalette)) << 2
+		priority := ppu.sprite(s.Sprite, Priority)
+
+		for k := uint16(0); k < 8 && start+k <= 256; k++ {
+			if start+k < first {
+				continue
+			}
+
+			index := uint16((s.TileHigh>>(7-k))&0x01)<<1 | uint16((s.TileLow>>(7-k))&0x01)
+
+			if index != 0 {
+				line[start+k] = spriteDot{0x3f10 | attribute | index, priority, i == 0 && ppu.spriteZero}
+			}
+		}
+	}
+}
+
+// renderScanline performs dots 1 through 256 of a visible scanline.  It
+// leaves the PPU in the same state as performing each dot with step
+// would, apart from the sprite slots which are reloaded before they are
+// next used and sprite evaluation which is done all at once.  Rather
+// than dispatching each dot it fetches each tile's bytes once per group
+// of 8 dots and then draws the group's pixels from the shift registers
+// held in local variables, reads the palette once rather than once per
+// pixel and draws sprites from a line buffer rather than shifting every
+// slot on every dot.
+func (ppu *RP2C02) renderScanline() {
+	tilesLow, tilesHigh, attributes := ppu.tilesLow, ppu.tilesHigh, ppu.attributes
+
+	if !ppu.rendering() {
+		// only the shift registers move, on dots 2 through 256
+		latch := uint16(ppu.attributeLatch) << 14
+
+		for cycle := 2; cycle <= 256; cycle++ {
+			attributes = attributes>>2 | latch
+		}
+
+		backdrop := ppu.Memory.Fetch(0x3f00)
+
+		for cycle := 1; cycle <= 256; cycle++ {
+			ppu.colors = append(ppu.colors, backdrop)
+
+			if ppu.Accesses != nil {
+				ppu.Accesses.read(RenderAccess, 0x3f00)
+			}
+		}
+
+		ppu.tilesLow, ppu.tilesHigh, ppu.attributes = 0, 0, attributes
+		ppu.cycle = 257
+
+		ppu.evaluateLine()
+
+		return
+	}
+
+	palette := [32]uint8{}
+	sprites := [257]spriteDot{}
+	line := [256]uint8{}
+
+	// transparent pixels of every palette show the backdrop.  Palette
+	// reads are counted per pixel below, as renderPixel counts them
+	for i := range palette {
+		if i&0x03 != 0 {
+			palette[i] = ppu.Memory.Fetch(0x3f00 | uint16(i))
+		} else {
+			palette[i] = ppu.Memory.Fetch(0x3f00)
+		}
+	}
+
+	ppu.spriteLine(&sprites)
+
+	background := ppu.mask(ShowBackground)
+	left := ppu.mask(ShowBackgroundLeft)
+	scroll := 8 + ppu.Registers.Scroll
+
+	for group := uint16(0); group < 32; group++ {
+		first := group<<3 + 1
+
+		if first >= 9 {
+			tilesLow = tilesLow&0xff00 | ppu.tilesLatch&0x00ff
+			tilesHigh = tilesHigh&0xff00 | ppu.tilesLatch>>8
+		}
+
+		// the attribute fetched on the group's third dot is shifted in
+		// from then on
+		latches := [2]uint16{uint16(ppu.attributeLatch) << 14}
+
+		ppu.cycle = first
+		ppu.loadPatternAddress()
+		ppu.cycle = first + 2
+		ppu.loadAttributeLatch()
+		ppu.cycle = first + 4
+		ppu.loadTileLow()
+		ppu.cycle = first + 6
+		ppu.loadTileHigh()
+
+		latches[1] = uint16(ppu.attributeLatch) << 14
+
+		pixels := [8]uint16{}
+
+		if first >= 9 {
+			// the group's 8 pixels are the bits of the shift registers
+			// below bit scroll and the 8 attributes at the bottom of
+			// attributes, which are then all shifted out at once
+			low, high := uint8(tilesLow>>(scroll-7)), uint8(tilesHigh>>(scroll-7))
+
+			if background {
+				for k := range pixels {
+					pixels[k] = 0x3f00 | (attributes>>(k<<1)&0x0003)<<2 |
+						uint16(high>>(7-k)&0x01)<<1 | uint16(low>>(7-k)&0x01)
+				}
+			}
+
+			tilesLow <<= 8
+			tilesHigh <<= 8
+			attributes = latches[0]>>14 | latches[0]>>12 | latches[1]>>10 | latches[1]>>8 |
+				latches[1]>>6 | latches[1]>>4 | latches[1]>>2 | latches[1]
+		} else {
+			for k := uint16(0); k < 8; k++ {
+				cycle := first + k
+
+				if background && (left || cycle > 8) {
+					pixels[k] = 0x3f00 | (attributes&0x0003)<<2 |
+						(tilesHigh>>scroll&0x0001)<<1 | tilesLow>>scroll&0x0001
+				}
+
+				if cycle >= 2 {
+					tilesLow <<= 1
+					tilesHigh <<= 1
+					attributes = attributes>>2 | latches[(k+6)>>3]
+				}
+			}
+		}
+
+		for k, address := range pixels {
+			cycle := first + uint16(k)
+
+			if sprite := &sprites[cycle]; sprite.address != 0 {
+				ppu.cycle = cycle
+				address = ppu.multiplex(address, sprite.address, sprite.priority, sprite.zero)
+			}
+
+			line[cycle-1] = palette[address&0x001f]
+
+			if ppu.Accesses != nil {
+				if address&0x0003 == 0 {
+					address = 0x3f00
+				}
+
+				ppu.Accesses.read(RenderAccess, address)
+			}
+		}
+
+		ppu.cycle = first + 7
+		ppu.incrementX()
+	}
+
+	ppu.incrementY()
+
+	ppu.tilesLow, ppu.tilesHigh, ppu.attributes = tilesLow, tilesHigh, attributes
+	ppu.colors = append(ppu.colors, line[:]...)
+	ppu.cycle = 257
+
+	ppu.evaluateLine()
+}
+
+// evaluateLine performs sprite evaluation for the whole scanline at
+// once.
+func (ppu *RP2C02) evaluateLine() {
+	if ppu.oam.evaluate(ppu.scanline, ppu.controller(SpriteSize)) {
+		ppu.spriteOverflow()
+	}
+}
+
+// renderTail performs dots 257 through 340 of a visible scanline, the
+// sprite fetches for the next scanline and the fetches of its first two
+// tiles, in the same order as step would.
+func (ppu *RP2C02) renderTail() {
+	rendering := ppu.rendering()
+
+	if rendering {
+		ppu.cycle = 257
+		ppu.reloadBackgroundTiles()
+
+		for slot := uint8(0); slot < 8; slot++ {
+			first := 257 + uint16(slot)<<3
+
+			ppu.cycle = first
+			ppu.fetchName(ppu.Registers.Address)
+
+			if slot == 0 {
+				ppu.transferX()
+			}
+
+			ppu.cycle = first + 2
+			ppu.fetchName(ppu.Registers.Address)
+			ppu.cycle = first + 4
+			ppu.loadSpriteLow(slot)
+			ppu.cycle = first + 6
+			ppu.loadSpriteHigh(slot)
+		}
+
+		ppu.Registers.OAMAddress = 0
+	}
+
+	ppu.shiftBackgroundTiles()
+
+	for ppu.cycle = 321; ppu.cycle <= 337; ppu.cycle++ {
+		if rendering {
+			if ppu.cycle == 329 || ppu.cycle == 337 {
+				ppu.reloadBackgroundTiles()
+			}
+
+			switch {
+			case ppu.cycle == 337:
+			case (ppu.cycle-1)&0x7 == 0:
+				ppu.loadPatternAddress()
+			case (ppu.cycle-1)&0x7 == 2:
+				ppu.loadAttributeLatch()
+			case (ppu.cycle-1)&0x7 == 4:
+				ppu.loadTileLow()
+			case (ppu.cycle-1)&0x7 == 6:
+				ppu.loadTileHigh()
+			case (ppu.cycle-1)&0x7 == 7:
+				ppu.incrementX()
+			}
+		}
+
+		if ppu.cycle >= 322 {
+			ppu.shiftBackgroundTiles()
+		}
+	}
+
+	ppu.cycle = CYCLES_PER_SCANLINE
+}
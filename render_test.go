@@ -0,0 +1,237 @@
+package rp2cgo2
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func newScene(renderer Renderer) *RP2C02 {
+	r := rand.New(rand.NewSource(1))
+
+	ppu := NewRP2C02(nil)
+	ppu.Renderer = renderer
+
+	for address := uint16(0x0000); address < 0x3000; address++ {
+		ppu.Memory.Store(address, uint8(r.Intn(256)))
+	}
+
+	for address := uint16(0x3f00); address < 0x3f20; address++ {
+		ppu.Memory.Store(address, uint8(r.Intn(64)))
+	}
+
+	for address := uint16(0x0000); address < 0x0100; address++ {
+		ppu.oam.Store(address, uint8(r.Intn(256)))
+	}
+
+	ppu.Registers.Controller = 0x10
+	ppu.Registers.Mask = uint8(ShowBackground | ShowSprites | ShowSpritesLeft)
+	ppu.Store(0x2005, 0x2b)
+	ppu.Store(0x2005, 0x11)
+
+	return ppu
+}
+
+// renderFrame drives a frame through Execute.  The PPU is given a
+// scanline of dots at a time, unless cpu is set, in which case it is
+// given a single dot and cpu runs once the PPU answers, as the CPU would.
+func renderFrame(ppu *RP2C02, cpu func(ppu *RP2C02)) []uint8 {
+	ppu.colors = []uint8{}
+
+	if cpu != nil {
+		ppu.Cycles = make(chan uint16, 1)
+	}
+
+	for ppu.scanline = 0; ppu.scanline < NUM_SCANLINES; ppu.scanline++ {
+		ppu.quota = CYCLES_PER_SCANLINE + 1
+
+		for ppu.cycle = 0; ppu.cycle < CYCLES_PER_SCANLINE; ppu.cycle++ {
+			if cpu != nil {
+				ppu.quota = 1
+			}
+
+			ppu.Execute()
+
+			if cpu != nil {
+				<-ppu.Cycles
+				cpu(ppu)
+			}
+		}
+	}
+
+	ppu.frame++
+
+	return ppu.colors
+}
+
+func compareRenderers(t *testing.T, cpu func(ppu *RP2C02)) {
+	dot := newScene(DotRenderer)
+	scanline := newScene(ScanlineRenderer)
+
+	for frame := 0; frame < 3; frame++ {
+		expected := renderFrame(dot, cpu)
+		actual := renderFrame(scanline, cpu)
+
+		if len(actual) != len(expected) {
+			t.Fatalf("Frame %d has %d pixels not %d", frame, len(actual), len(expected))
+		}
+
+		for i := range actual {
+			if actual[i] != expected[i] {
+				t.Fatalf("Frame %d pixel %d,%d is %02X not %02X", frame, i%256, i/256, actual[i], expected[i])
+			}
+		}
+
+		if scanline.Registers != dot.Registers {
+			t.Errorf("Frame %d registers are %+v not %+v", frame, scanline.Registers, dot.Registers)
+		}
+	}
+}
+
+func TestScanlineRenderer(t *testing.T) {
+	compareRenderers(t, nil)
+}
+
+func TestScanlineRendererFallback(t *testing.T) {
+	compareRenderers(t, func(ppu *RP2C02) {
+		switch {
+		case ppu.scanline == 60 && ppu.cycle == 100:
+			ppu.Store(0x2001, uint8(ShowBackground|ShowBackgroundLeft))
+		case ppu.scanline == 120 && ppu.cycle == 300:
+			ppu.Store(0x2006, 0x04)
+			ppu.Store(0x2006, 0x00)
+		case ppu.scanline == 180 && ppu.cycle == 40:
+			ppu.Fetch(0x2002)
+		case ppu.scanline == 200 && ppu.cycle == 200:
+			ppu.Store(0x2001, uint8(ShowBackground|ShowSprites|ShowSpritesLeft))
+			ppu.Store(0x2005, 0x2b)
+			ppu.Store(0x2005, 0x11)
+		}
+	})
+}
+
+func TestScanlineRendererQuota(t *testing.T) {
+	answers := map[Renderer][][2]uint16{}
+	frames := map[Renderer][]uint8{}
+
+	for _, renderer := range []Renderer{DotRenderer, ScanlineRenderer} {
+		ppu := newScene(renderer)
+		ppu.Cycles = make(chan uint16, 1)
+		ppu.colors = []uint8{}
+
+		for ppu.scanline = 0; ppu.scanline < NUM_SCANLINES; ppu.scanline++ {
+			for ppu.cycle = 0; ppu.cycle < CYCLES_PER_SCANLINE; ppu.cycle++ {
+				if ppu.quota == 0 {
+					ppu.Cycles <- 100
+				}
+
+				ppu.Execute()
+
+				if ppu.quota == 0 {
+					<-ppu.Cycles
+					answers[renderer] = append(answers[renderer], [2]uint16{ppu.scanline, ppu.cycle})
+				}
+			}
+		}
+
+		frames[renderer] = ppu.colors
+	}
+
+	dot, scanline := answers[DotRenderer], answers[ScanlineRenderer]
+
+	if len(scanline) != len(dot) {
+		t.Fatalf("Scanline renderer answered %d times not %d", len(scanline), len(dot))
+	}
+
+	for i := range dot {
+		if scanline[i] != dot[i] {
+			t.Fatalf("Answer %d is at %d,%d not %d,%d", i, scanline[i][0], scanline[i][1], dot[i][0], dot[i][1])
+		}
+	}
+
+	if !bytes.Equal(frames[ScanlineRenderer], frames[DotRenderer]) {
+		t.Error("Scanline renderer frame does not match")
+	}
+}
+
+func TestRenderFrame(t *testing.T) {
+	dot := newScene(DotRenderer)
+	scanline := newScene(ScanlineRenderer)
+
+	for frame := 0; frame < 3; frame++ {
+		expected := renderFrame(dot, nil)
+		actual := scanline.RenderFrame()
+
+		if len(actual) != len(expected) {
+			t.Fatalf("Frame %d has %d pixels not %d", frame, len(actual), len(expected))
+		}
+
+		for i := range actual {
+			if actual[i] != expected[i] {
+				t.Fatalf("Frame %d pixel %d,%d is %02X not %02X", frame, i%256, i/256, actual[i], expected[i])
+			}
+		}
+	}
+}
+
+func benchmarkRenderer(b *testing.B, renderer Renderer) {
+	ppu := newScene(renderer)
+
+	for i := 0; i < b.N; i++ {
+		renderFrame(ppu, nil)
+	}
+}
+
+func BenchmarkDotRenderer(b *testing.B) {
+	benchmarkRenderer(b, DotRenderer)
+}
+
+func BenchmarkScanlineRenderer(b *testing.B) {
+	benchmarkRenderer(b, ScanlineRenderer)
+}
+
+func BenchmarkRenderFrame(b *testing.B) {
+	ppu := newScene(ScanlineRenderer)
+
+	for i := 0; i < b.N; i++ {
+		ppu.RenderFrame()
+	}
+}
+
+// benchmarkExecute drives frames through Execute as Run does, giving
+// the PPU quota dots at a time over Cycles.
+func benchmarkExecute(b *testing.B, quota uint16) {
+	ppu := newScene(ScanlineRenderer)
+	ppu.Cycles = make(chan uint16, 1)
+
+	for i := 0; i < b.N; i++ {
+		ppu.colors = ppu.colors[:0]
+
+		for ppu.scanline = 0; ppu.scanline < NUM_SCANLINES; ppu.scanline++ {
+			for ppu.cycle = 0; ppu.cycle < CYCLES_PER_SCANLINE; ppu.cycle++ {
+				if ppu.quota == 0 {
+					ppu.Cycles <- quota
+				}
+
+				ppu.Execute()
+
+				if ppu.quota == 0 {
+					<-ppu.Cycles
+				}
+			}
+		}
+
+		ppu.frame++
+	}
+}
+
+// BenchmarkExecute gives the PPU a scanline of dots at a time.
+func BenchmarkExecute(b *testing.B) {
+	benchmarkExecute(b, CYCLES_PER_SCANLINE)
+}
+
+// BenchmarkExecuteInstruction gives the PPU the 9 dots of a 3 cycle CPU
+// instruction at a time.
+func BenchmarkExecuteInstruction(b *testing.B) {
+	benchmarkExecute(b, 9)
+}
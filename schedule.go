@@ -0,0 +1,173 @@
+package rp2cgo2
+
+type Region uint8
+
+const (
+	NTSC Region = iota
+	PAL
+	Dendy
+)
+
+func (r Region) String() string {
+	switch r {
+	case NTSC:
+		return "NTSC"
+	case PAL:
+		return "PAL"
+	case Dendy:
+		return "Dendy"
+	}
+
+	return "Unknown"
+}
+
+type scanlineKind uint8
+
+const (
+	visibleScanline scanlineKind = iota
+	postRenderScanline
+	vblankStartScanline
+	vblankScanline
+	preRenderScanline
+	numScanlineKinds
+)
+
+type dotOp uint32
+
+const (
+	opFetchName dotOp = 1 << iota
+	opFetchAttribute
+	opFetchTileLow
+	opFetchTileHigh
+	opReloadTiles
+	opShiftTiles
+	opIncrementX
+	opIncrementY
+	opTransferX
+	opTransferY
+	opRenderPixel
+	opEvaluateSprites
+	opFetchGarbageName
+	opFetchSpriteLow
+	opFetchSpriteHigh
+	opResetOAMAddress
+	opSetVBlank
+	opClearStatus
+	opOddFrameSkip
+)
+
+type dot struct {
+	ops    dotOp
+	sprite uint8
+}
+
+// schedule holds, for every dot of every kind of scanline, the
+// operations the PPU performs on that dot, along with the kind of each
+// scanline in a frame.  Regions differ only in their schedule.
+type schedule struct {
+	scanlines []scanlineKind
+	dots      [numScanlineKinds][CYCLES_PER_SCANLINE]dot
+}
+
+var schedules = [...]*schedule{
+	NTSC:  newSchedule(VBLANK_SCANLINE, 261, true),
+	PAL:   newSchedule(VBLANK_SCANLINE, 311, false),
+	Dendy: newSchedule(291, 311, false),
+}
+
+func newSchedule(vblank, preRender uint16, oddFrameSkip bool) *schedule {
+	s := &schedule{
+		scanlines: make([]scanlineKind, preRender+1),
+	}
+
+	for scanline := range s.scanlines {
+		switch {
+		case scanline < 240:
+			s.scanlines[scanline] = visibleScanline
+		case scanline < int(vblank):
+			s.scanlines[scanline] = postRenderScanline
+		case scanline == int(vblank):
+			s.scanlines[scanline] = vblankStartScanline
+		case scanline < int(preRender):
+			s.scanlines[scanline] = vblankScanline
+		default:
+			s.scanlines[scanline] = preRenderScanline
+		}
+	}
+
+	for _, kind := range []scanlineKind{visibleScanline, preRenderScanline} {
+		dots := &s.dots[kind]
+
+		for cycle := uint16(0); cycle < CYCLES_PER_SCANLINE; cycle++ {
+			ops := dotOp(0)
+
+			if (cycle >= 1 && cycle <= 256) || (cycle >= 321 && cycle <= 336) {
+				switch (cycle - 1) & 0x7 {
+				case 0:
+					ops |= opFetchName
+				case 2:
+					ops |= opFetchAttribute
+				case 4:
+					ops |= opFetchTileLow
+				case 6:
+					ops |= opFetchTileHigh
+				case 7:
+					ops |= opIncrementX
+				}
+			}
+
+			if ((cycle >= 9 && cycle <= 257) || (cycle >= 329 && cycle <= 337)) && (cycle-1)&0x7 == 0 {
+				ops |= opReloadTiles
+			}
+
+			if (cycle >= 2 && cycle <= 257) || (cycle >= 322 && cycle <= 337) {
+				ops |= opShiftTiles
+			}
+
+			switch cycle {
+			case 1:
+				if kind == preRenderScanline {
+					ops |= opClearStatus
+				}
+			case 256:
+				ops |= opIncrementY
+			case 257:
+				ops |= opTransferX
+			case 339:
+				if kind == preRenderScanline && oddFrameSkip {
+					ops |= opOddFrameSkip
+				}
+			}
+
+			if kind == preRenderScanline && cycle >= 280 && cycle <= 304 {
+				ops |= opTransferY
+			}
+
+			if kind == visibleScanline && cycle >= 1 && cycle <= 256 {
+				ops |= opRenderPixel | opEvaluateSprites
+			}
+
+			dots[cycle].ops = ops
+		}
+
+		// each sprite slot takes 8 dots: two garbage nametable
+		// fetches then the pattern low and high bytes
+		for cycle := uint16(257); cycle <= 320; cycle++ {
+			dots[cycle].ops |= opResetOAMAddress
+			dots[cycle].sprite = uint8((cycle - 257) >> 3)
+
+			switch (cycle - 257) & 0x7 {
+			case 0, 2:
+				dots[cycle].ops |= opFetchGarbageName
+			case 4:
+				dots[cycle].ops |= opFetchSpriteLow
+			case 6:
+				dots[cycle].ops |= opFetchSpriteHigh
+			}
+		}
+	}
+
+	s.dots[vblankStartScanline][1].ops = opSetVBlank
+
+	return s
+}
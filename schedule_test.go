@@ -0,0 +1,192 @@
+package rp2cgo2
+
+import "testing"
+
+func scheduleCycles(s *schedule, kind scanlineKind, op dotOp) (cycles []uint16) {
+	for cycle := uint16(0); cycle < CYCLES_PER_SCANLINE; cycle++ {
+		if s.dots[kind][cycle].ops&op != 0 {
+			cycles = append(cycles, cycle)
+		}
+	}
+
+	return
+}
+
+func TestScheduleScanlines(t *testing.T) {
+	for _, test := range []struct {
+		region    Region
+		scanlines int
+		vblank    uint16
+		preRender uint16
+	}{
+		{NTSC, 262, 241, 261},
+		{PAL, 312, 241, 311},
+		{Dendy, 312, 291, 311},
+	} {
+		s := schedules[test.region]
+
+		if len(s.scanlines) != test.scanlines {
+			t.Errorf("%v has %d scanlines not %d", test.region, len(s.scanlines), test.scanlines)
+		}
+
+		if s.scanlines[0] != visibleScanline || s.scanlines[239] != visibleScanline {
+			t.Errorf("%v scanlines 0-239 are not visible", test.region)
+		}
+
+		if s.scanlines[240] != postRenderScanline {
+			t.Errorf("%v scanline 240 is not post-render", test.region)
+		}
+
+		if s.scanlines[test.vblank] != vblankStartScanline {
+			t.Errorf("%v scanline %d does not start vblank", test.region, test.vblank)
+		}
+
+		if s.scanlines[test.preRender] != preRenderScanline {
+			t.Errorf("%v scanline %d is not pre-render", test.region, test.preRender)
+		}
+	}
+}
+
+func TestScheduleFetches(t *testing.T) {
+	s := schedules[NTSC]
+
+	for _, test := range []struct {
+		op    dotOp
+		first uint16
+	}{
+		{opFetchName, 1},
+		{opFetchAttribute, 3},
+		{opFetchTileLow, 5},
+		{opFetchTileHigh, 7},
+		{opIncrementX, 8},
+	} {
+		expected := []uint16{}
+
+		for cycle := test.first; cycle <= 256; cycle += 8 {
+			expected = append(expected, cycle)
+		}
+
+		expected = append(expected, test.first+320, test.first+328)
+
+		for _, kind := range []scanlineKind{visibleScanline, preRenderScanline} {
+			actual := scheduleCycles(s, kind, test.op)
+
+			if len(actual) != len(expected) {
+				t.Errorf("Op %04X has %d cycles not %d", test.op, len(actual), len(expected))
+				continue
+			}
+
+			for i := range actual {
+				if actual[i] != expected[i] {
+					t.Errorf("Op %04X is on cycle %d not %d", test.op, actual[i], expected[i])
+				}
+			}
+		}
+	}
+
+	if cycles := scheduleCycles(s, postRenderScanline, 0xffffffff); len(cycles) != 0 {
+		t.Errorf("Post-render scanline has ops on cycles %v", cycles)
+	}
+
+	if cycles := scheduleCycles(s, visibleScanline, opTransferY); len(cycles) != 0 {
+		t.Errorf("Visible scanline transfers Y on cycles %v", cycles)
+	}
+
+	if cycles := scheduleCycles(s, preRenderScanline, opTransferY); len(cycles) != 25 {
+		t.Errorf("Pre-render scanline transfers Y on cycles %v", cycles)
+	}
+}
+
+func TestScheduleSpriteFetches(t *testing.T) {
+	s := schedules[NTSC]
+
+	for _, test := range []struct {
+		op     dotOp
+		offset uint16
+		count  int
+	}{
+		{opFetchGarbageName, 0, 16},
+		{opFetchSpriteLow, 4, 8},
+		{opFetchSpriteHigh, 6, 8},
+	} {
+		for _, kind := range []scanlineKind{visibleScanline, preRenderScanline} {
+			actual := scheduleCycles(s, kind, test.op)
+
+			if len(actual) != test.count {
+				t.Errorf("Op %04X has %d cycles not %d", test.op, len(actual), test.count)
+				continue
+			}
+
+			for _, cycle := range actual {
+				if cycle < 257 || cycle > 320 || (cycle-257)&0x7 != test.offset&0x7 && (cycle-257)&0x7 != test.offset+2 {
+					t.Errorf("Op %04X is on cycle %d", test.op, cycle)
+				}
+
+				if slot := s.dots[kind][cycle].sprite; slot != uint8((cycle-257)>>3) {
+					t.Errorf("Op %04X on cycle %d loads slot %d", test.op, cycle, slot)
+				}
+			}
+		}
+	}
+
+	if cycles := scheduleCycles(s, visibleScanline, opResetOAMAddress); len(cycles) != 64 || cycles[0] != 257 {
+		t.Errorf("OAMADDR is reset on cycles %v", cycles)
+	}
+}
+
+func TestScheduleOddFrameSkip(t *testing.T) {
+	if cycles := scheduleCycles(schedules[NTSC], preRenderScanline, opOddFrameSkip); len(cycles) != 1 || cycles[0] != 339 {
+		t.Errorf("NTSC skips a dot on odd frames on cycles %v not [339]", cycles)
+	}
+
+	if len(scheduleCycles(schedules[NTSC], visibleScanline, opOddFrameSkip)) != 0 {
+		t.Error("NTSC skips a dot on a visible scanline")
+	}
+
+	if len(scheduleCycles(schedules[PAL], preRenderScanline, opOddFrameSkip)) != 0 {
+		t.Error("PAL skips a dot on odd frames")
+	}
+}
+
+func TestScheduleVBlank(t *testing.T) {
+	nmi := false
+
+	ppu := NewRP2C02(func(state bool) { nmi = state })
+	ppu.Region = Dendy
+	ppu.schedule = schedules[Dendy]
+	ppu.Registers.Controller = uint8(NMIOnVBlank)
+	ppu.quota = 0xffff
+
+	ppu.scanline = 241
+	ppu.cycle = 1
+	ppu.Execute()
+
+	if ppu.status(VBlankStarted) || nmi {
+		t.Error("Dendy VBlank started on scanline 241")
+	}
+
+	ppu.scanline = 291
+	ppu.Execute()
+
+	if !ppu.status(VBlankStarted) || !nmi {
+		t.Error("Dendy VBlank did not start on scanline 291")
+	}
+}
+
+func BenchmarkFrame(b *testing.B) {
+	ppu := NewRP2C02(nil)
+	ppu.Registers.Mask = uint8(ShowBackground | ShowSprites | ShowBackgroundLeft | ShowSpritesLeft)
+
+	for i := 0; i < b.N; i++ {
+		ppu.colors = ppu.colors[:0]
+
+		for ppu.scanline = 0; ppu.scanline < NUM_SCANLINES; ppu.scanline++ {
+			for ppu.cycle = 0; ppu.cycle < CYCLES_PER_SCANLINE; ppu.cycle++ {
+				ppu.step()
+				ppu.skipDot()
+			}
+		}
+
+		ppu.frame++
+	}
+}
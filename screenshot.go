@@ -0,0 +1,114 @@
+package rp2cgo2
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// ScreenshotOptions control how a frame is turned into an image.
+// CropOverscan removes the top and bottom 8 lines hidden by most NTSC
+// televisions, CropLeft and CropRight remove columns from either side
+// and AspectCorrect stretches the image horizontally to the NES's 8:7
+// pixel aspect ratio.
+type ScreenshotOptions struct {
+	CropOverscan  bool
+	CropLeft      int
+	CropRight     int
+	AspectCorrect bool
+}
+
+// Image returns frame as a paletted image using palette.  Frames from
+// the PPU always hold all 256x240 pixels, any missing from a shorter
+// frame are left as index 0x00.
+func (frame *Frame) Image(palette *Palette, options *ScreenshotOptions) *image.Paletted {
+	if options == nil {
+		options = &ScreenshotOptions{}
+	}
+
+	colors := make(color.Palette, len(palette))
+
+	for i := range palette {
+		colors[i] = palette[i]
+	}
+
+	left, right, top, bottom := options.CropLeft, 256-options.CropRight, 0, 240
+
+	if options.CropOverscan {
+		top, bottom = 8, 232
+	}
+
+	if left < 0 || right > 256 || left >= right {
+		left, right = 0, 256
+	}
+
+	width := right - left
+
+	if options.AspectCorrect {
+		width = (width*8 + 3) / 7
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, width, bottom-top), colors)
+
+	for y := top; y < bottom; y++ {
+		row := img.Pix[(y-top)*img.Stride:]
+
+		for x := 0; x < width; x++ {
+			sx := left + x
+
+			if options.AspectCorrect {
+				sx = left + x*7/8
+			}
+
+			if i := y*256 + sx; i < len(frame.Pixels) {
+				row[x] = frame.Pixels[i] & 0x3f
+			}
+		}
+	}
+
+	return img
+}
+
+// padFrame fills in the start of a frame that the PPU only began part
+// way through, such as one finished by RenderFrame after a Reset, with
+// the backdrop color, so every frame handed on holds 256x240 pixels.
+// Every visible dot outputs a pixel, so no other pixels can be missing.
+func (ppu *RP2C02) padFrame() {
+	missing := 256*240 - len(ppu.colors)
+
+	if missing <= 0 {
+		return
+	}
+
+	pixels := make([]uint8, missing, 256*240)
+	backdrop := ppu.Memory.Fetch(0x3f00)
+
+	for i := range pixels {
+		pixels[i] = backdrop
+	}
+
+	ppu.colors = append(pixels, ppu.colors...)
+}
+
+// keepLast copies the completed frame for Screenshot.
+func (ppu *RP2C02) keepLast() {
+	ppu.lastLock.Lock()
+	defer ppu.lastLock.Unlock()
+
+	ppu.last = Frame{Number: ppu.frame, Pixels: append(ppu.last.Pixels[:0], ppu.colors...)}
+}
+
+// Screenshot returns the last completed frame.  It is safe to call from
+// any goroutine while the PPU runs.
+func (ppu *RP2C02) Screenshot(options *ScreenshotOptions) image.Image {
+	ppu.lastLock.Lock()
+	defer ppu.lastLock.Unlock()
+
+	return ppu.last.Image(ppu.Palette, options)
+}
+
+// ScreenshotPNG writes Screenshot to w as a PNG.
+func (ppu *RP2C02) ScreenshotPNG(w io.Writer, options *ScreenshotOptions) error {
+	return png.Encode(w, ppu.Screenshot(options))
+}
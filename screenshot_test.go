@@ -0,0 +1,148 @@
+package rp2cgo2
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestScreenshot(t *testing.T) {
+	ppu := newScene(DotRenderer)
+	colors := ppu.RenderFrame()
+
+	for _, test := range []struct {
+		options       *ScreenshotOptions
+		width, height int
+	}{
+		{nil, 256, 240},
+		{&ScreenshotOptions{CropOverscan: true}, 256, 224},
+		{&ScreenshotOptions{CropOverscan: true, CropLeft: 8, CropRight: 8}, 240, 224},
+		{&ScreenshotOptions{AspectCorrect: true}, 293, 240},
+	} {
+		img := ppu.Screenshot(test.options)
+
+		if bounds := img.Bounds(); bounds.Dx() != test.width || bounds.Dy() != test.height {
+			t.Errorf("%+v: Screenshot is %dx%d not %dx%d", test.options, bounds.Dx(), bounds.Dy(), test.width, test.height)
+		}
+	}
+
+	img := ppu.Screenshot(&ScreenshotOptions{CropOverscan: true, CropLeft: 8})
+
+	if c := img.At(0, 0); c != DefaultPalette[colors[8*256+8]&0x3f] {
+		t.Errorf("Pixel is %v not %v", c, DefaultPalette[colors[8*256+8]&0x3f])
+	}
+
+	img = ppu.Screenshot(&ScreenshotOptions{AspectCorrect: true})
+
+	if c := img.At(292, 239); c != DefaultPalette[colors[239*256+255]&0x3f] {
+		t.Errorf("Pixel is %v not %v", c, DefaultPalette[colors[239*256+255]&0x3f])
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := ppu.ScreenshotPNG(buf, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := png.Decode(buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.At(100, 100) != ppu.Screenshot(nil).At(100, 100) {
+		t.Error("PNG does not match screenshot")
+	}
+}
+
+func TestScreenshotRenderingDisabled(t *testing.T) {
+	for _, renderer := range []Renderer{DotRenderer, ScanlineRenderer} {
+		ppu := newScene(renderer)
+		backdrop := ppu.Memory.Fetch(0x3f00)
+
+		// rendering is disabled from scanline 100 on
+		colors := renderFrame(ppu, func(ppu *RP2C02) {
+			if ppu.scanline == 100 && ppu.cycle == 0 {
+				ppu.Store(0x2001, 0x00)
+			}
+		})
+
+		if len(colors) != 256*240 {
+			t.Fatalf("%v: Frame has %d pixels not %d", renderer, len(colors), 256*240)
+		}
+
+		for _, i := range []int{100 * 256, 150*256 + 128, 256*240 - 1} {
+			if colors[i] != backdrop {
+				t.Errorf("%v: Pixel %d is %02X not the backdrop %02X", renderer, i, colors[i], backdrop)
+			}
+		}
+
+		ppu.endFrame()
+
+		frame := &Frame{Pixels: colors}
+		img, screenshot := frame.Image(ppu.Palette, nil), ppu.Screenshot(nil)
+
+		for _, p := range []image.Point{{0, 0}, {128, 99}, {128, 100}, {255, 239}} {
+			if img.At(p.X, p.Y) != screenshot.At(p.X, p.Y) {
+				t.Errorf("%v: Pixel %v of the frame is %v but %v in the screenshot", renderer, p, img.At(p.X, p.Y), screenshot.At(p.X, p.Y))
+			}
+		}
+	}
+}
+
+func TestScreenshotBackdrop(t *testing.T) {
+	ppu := NewRP2C02(nil)
+	ppu.Memory.Store(0x3f00, 0x21)
+	ppu.RenderFrame()
+
+	img := ppu.Screenshot(nil)
+
+	for _, p := range []image.Point{{0, 0}, {128, 120}, {255, 239}} {
+		if c := img.At(p.X, p.Y); c != DefaultPalette[0x21] {
+			t.Errorf("Pixel %v is %v not the backdrop %v", p, c, DefaultPalette[0x21])
+		}
+	}
+}
+
+func TestScreenshotRun(t *testing.T) {
+	ppu := NewRP2C02WithConfig(Config{Mirroring: FourScreen})
+	ppu.Registers.Mask = uint8(ShowBackground)
+
+	done := make(chan bool)
+	defer close(done)
+
+	go ppu.Run()
+
+	go func() {
+		for {
+			select {
+			case ppu.Cycles <- 0xffff:
+				<-ppu.Cycles
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	timeout := time.After(10 * time.Second)
+
+	for {
+		select {
+		case <-timeout:
+			t.Fatal("Timed out waiting for frame 3")
+		default:
+		}
+
+		img := ppu.Screenshot(nil)
+
+		if bounds := img.Bounds(); bounds.Dx() != 256 || bounds.Dy() != 240 {
+			t.Fatalf("Screenshot is %v not 256x240", bounds)
+		}
+
+		if frame := ppu.Frames.Latest(); frame != nil && frame.Number >= 3 {
+			break
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package rp2cgo2
+
+// LoopyAddress is a decoded 15-bit VRAM address as used by the "loopy"
+// v and t registers:
+//
+//	.yyy NN YYYYY XXXXX
+//	 ||| || ||||| +++++-- coarse X scroll
+//	 ||| || +++++-------- coarse Y scroll
+//	 ||| ++-------------- nametable select
+//	 +++----------------- fine Y scroll
+type LoopyAddress struct {
+	Address   uint16
+	CoarseX   uint8
+	CoarseY   uint8
+	Nametable uint8
+	FineY     uint8
+}
+
+func NewLoopyAddress(address uint16) LoopyAddress {
+	return LoopyAddress{
+		Address:   address & 0x7fff,
+		CoarseX:   uint8(address & 0x001f),
+		CoarseY:   uint8((address & 0x03e0) >> 5),
+		Nametable: uint8((address & 0x0c00) >> 10),
+		FineY:     uint8((address & 0x7000) >> 12),
+	}
+}
+
+// ScrollState is a snapshot of the PPU's internal scroll registers.  V
+// is the current VRAM address (Registers.Address), T the temporary VRAM
+// address written through $2000, $2005 and $2006, FineX the fine X
+// scroll (Registers.Scroll) and WriteToggle the shared $2005/$2006
+// first/second write latch.
+type ScrollState struct {
+	V           LoopyAddress
+	T           LoopyAddress
+	FineX       uint8
+	WriteToggle bool
+}
+
+// X returns the horizontal scroll in pixels across all four
+// nametables described by T and FineX.
+func (s ScrollState) X() uint16 {
+	return uint16(s.T.Nametable&0x01)<<8 | uint16(s.T.CoarseX)<<3 | uint16(s.FineX)
+}
+
+// Y returns the vertical scroll in pixels across all four nametables
+// described by T.
+func (s ScrollState) Y() uint16 {
+	return uint16(s.T.Nametable>>1)*240 + uint16(s.T.CoarseY)<<3 + uint16(s.T.FineY)
+}
+
+func (ppu *RP2C02) ScrollState() ScrollState {
+	return ScrollState{
+		V:           NewLoopyAddress(ppu.Registers.Address),
+		T:           NewLoopyAddress(ppu.latchAddress),
+		FineX:       uint8(ppu.Registers.Scroll & 0x07),
+		WriteToggle: ppu.latch,
+	}
+}
@@ -0,0 +1,69 @@
+package rp2cgo2
+
+import "testing"
+
+func TestNewLoopyAddress(t *testing.T) {
+	// .yyy NN YYYYY XXXXX
+	// .101 10 10011 01110
+	a := NewLoopyAddress(0x5a6e)
+
+	if a.CoarseX != 0x0e {
+		t.Errorf("CoarseX is %02X not 0x0e", a.CoarseX)
+	}
+
+	if a.CoarseY != 0x13 {
+		t.Errorf("CoarseY is %02X not 0x13", a.CoarseY)
+	}
+
+	if a.Nametable != 0x02 {
+		t.Errorf("Nametable is %02X not 0x02", a.Nametable)
+	}
+
+	if a.FineY != 0x05 {
+		t.Errorf("FineY is %02X not 0x05", a.FineY)
+	}
+}
+
+func TestScrollState(t *testing.T) {
+	ppu := NewRP2C02(nil)
+
+	ppu.Store(0x2000, 0x01)
+	ppu.Store(0x2005, 0x7d) // X = 15 * 8 + 5
+	ppu.Store(0x2005, 0x5e) // Y = 11 * 8 + 6
+
+	s := ppu.ScrollState()
+
+	if s.T.CoarseX != 15 || s.FineX != 5 {
+		t.Errorf("Coarse X is %d, fine X is %d not 15, 5", s.T.CoarseX, s.FineX)
+	}
+
+	if s.T.CoarseY != 11 || s.T.FineY != 6 {
+		t.Errorf("Coarse Y is %d, fine Y is %d not 11, 6", s.T.CoarseY, s.T.FineY)
+	}
+
+	if s.T.Nametable != 1 {
+		t.Errorf("Nametable is %d not 1", s.T.Nametable)
+	}
+
+	if s.X() != 256+0x7d || s.Y() != 0x5e {
+		t.Errorf("Scroll is %d,%d not %d,%d", s.X(), s.Y(), 256+0x7d, 0x5e)
+	}
+
+	if s.WriteToggle {
+		t.Error("WriteToggle is not false")
+	}
+
+	ppu.Store(0x2006, 0x3f)
+	s = ppu.ScrollState()
+
+	if !s.WriteToggle {
+		t.Error("WriteToggle is not true")
+	}
+
+	ppu.Store(0x2006, 0x00)
+	s = ppu.ScrollState()
+
+	if s.V.Address != 0x3f00 || s.V != s.T {
+		t.Errorf("V is %04X not 0x3f00", s.V.Address)
+	}
+}
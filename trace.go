@@ -0,0 +1,82 @@
+package rp2cgo2
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+type TraceMode uint8
+
+const (
+	TraceDots TraceMode = iota
+	TraceFetches
+)
+
+// Tracer writes one line per dot, or one line per rendering fetch,
+// describing the PPU's internal state.  Lines are buffered, call Flush
+// once tracing is done.
+type Tracer struct {
+	w       *bufio.Writer
+	Mode    TraceMode
+	err     error
+	fetched bool
+	address uint16
+	value   uint8
+}
+
+func NewTracer(w io.Writer, mode TraceMode) *Tracer {
+	return &Tracer{
+		w:    bufio.NewWriter(w),
+		Mode: mode,
+	}
+}
+
+func (tracer *Tracer) Flush() error {
+	if tracer.err != nil {
+		return tracer.err
+	}
+
+	return tracer.w.Flush()
+}
+
+func (tracer *Tracer) fetch(ppu *RP2C02, address uint16, value uint8) {
+	tracer.fetched = true
+	tracer.address = address
+	tracer.value = value
+
+	if tracer.Mode == TraceFetches {
+		tracer.line(ppu)
+	}
+}
+
+func (tracer *Tracer) dot(ppu *RP2C02) {
+	if tracer.Mode == TraceDots {
+		tracer.line(ppu)
+	}
+
+	tracer.fetched = false
+}
+
+func (tracer *Tracer) line(ppu *RP2C02) {
+	if tracer.err != nil {
+		return
+	}
+
+	w := 0
+
+	if ppu.latch {
+		w = 1
+	}
+
+	fetch := "ADDR:---- VAL:--"
+
+	if tracer.fetched {
+		fetch = fmt.Sprintf("ADDR:%04X VAL:%02X", tracer.address, tracer.value)
+	}
+
+	_, tracer.err = fmt.Fprintf(tracer.w,
+		"F:%-5d SL:%-3d CYC:%-3d V:%04X T:%04X X:%d W:%d LO:%04X HI:%04X AT:%04X %s\n",
+		ppu.frame, ppu.scanline, ppu.cycle, ppu.Registers.Address, ppu.latchAddress,
+		ppu.Registers.Scroll, w, ppu.tilesLow, ppu.tilesHigh, ppu.attributes, fetch)
+}
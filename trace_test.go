@@ -0,0 +1,81 @@
+package rp2cgo2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func traceDots(mode TraceMode) []string {
+	buf := &bytes.Buffer{}
+
+	ppu := NewRP2C02(nil)
+	ppu.Tracer = NewTracer(buf, mode)
+	ppu.Registers.Mask = uint8(ShowBackground)
+	ppu.quota = 0xffff
+
+	for ppu.cycle = 1; ppu.cycle <= 8; ppu.cycle++ {
+		ppu.Execute()
+	}
+
+	ppu.Tracer.Flush()
+
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestTraceDots(t *testing.T) {
+	lines := traceDots(TraceDots)
+
+	if len(lines) != 8 {
+		t.Fatalf("Trace has %d lines not 8", len(lines))
+	}
+
+	if !strings.Contains(lines[0], "CYC:1 ") || !strings.Contains(lines[0], "ADDR:2000") {
+		t.Errorf("Line is %q", lines[0])
+	}
+
+	if !strings.Contains(lines[1], "ADDR:----") {
+		t.Errorf("Line is %q", lines[1])
+	}
+}
+
+func TestTraceFetches(t *testing.T) {
+	lines := traceDots(TraceFetches)
+
+	if len(lines) != 4 {
+		t.Fatalf("Trace has %d lines not 4", len(lines))
+	}
+
+	for i, address := range []string{"ADDR:2000", "ADDR:23C0", "ADDR:0000", "ADDR:0008"} {
+		if !strings.Contains(lines[i], address) {
+			t.Errorf("Line is %q, expected %v", lines[i], address)
+		}
+	}
+}
+
+func TestTraceScanlineRenderer(t *testing.T) {
+	traces := [2]*bytes.Buffer{}
+
+	for i, renderer := range []Renderer{DotRenderer, ScanlineRenderer} {
+		traces[i] = &bytes.Buffer{}
+
+		ppu := newScene(renderer)
+		ppu.Tracer = NewTracer(traces[i], TraceDots)
+
+		renderFrame(ppu, nil)
+		ppu.Tracer.Flush()
+	}
+
+	expected := strings.Split(traces[0].String(), "\n")
+	actual := strings.Split(traces[1].String(), "\n")
+
+	if len(actual) != len(expected) {
+		t.Fatalf("Trace has %d lines not %d", len(actual), len(expected))
+	}
+
+	for i := range actual {
+		if actual[i] != expected[i] {
+			t.Fatalf("Line is %q not %q", actual[i], expected[i])
+		}
+	}
+}